@@ -0,0 +1,559 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+	"github.com/y7ut/mcp-tavily-search/internal/logging"
+	"github.com/y7ut/mcp-tavily-search/internal/tool"
+	"github.com/y7ut/mcp-tavily-search/internal/tracing"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the config file of the run command, yaml or toml by the file extension,
+// unset values fall back to the flags, the environment variables and the defaults
+type Config struct {
+	APIKeys        []string `yaml:"api_keys" toml:"api_keys"`
+	IncludeDomains []string `yaml:"include_domains" toml:"include_domains"`
+	ExcludeDomains []string `yaml:"exclude_domains" toml:"exclude_domains"`
+
+	Keys      KeysConfig            `yaml:"keys" toml:"keys"`
+	Transport TransportConfig       `yaml:"transport" toml:"transport"`
+	Cache     CacheConfig           `yaml:"cache" toml:"cache"`
+	Tavily    TavilyConfig          `yaml:"tavily" toml:"tavily"`
+	Image     ImageConfig           `yaml:"image" toml:"image"`
+	Tools     map[string]ToolConfig `yaml:"tools" toml:"tools"`
+	Log       LogConfig             `yaml:"log" toml:"log"`
+	Metrics   MetricsConfig         `yaml:"metrics" toml:"metrics"`
+	Tracing   TracingConfig         `yaml:"tracing" toml:"tracing"`
+	Budget    BudgetConfig          `yaml:"budget" toml:"budget"`
+	RateLimit RateLimitConfig       `yaml:"rate_limit" toml:"rate_limit"`
+}
+
+// KeysConfig is the key pool settings
+type KeysConfig struct {
+	Strategy *string `yaml:"strategy" toml:"strategy"`
+	Cooldown *string `yaml:"cooldown" toml:"cooldown"`
+}
+
+// TransportConfig is the transport settings
+type TransportConfig struct {
+	Mode           *string  `yaml:"mode" toml:"mode"`
+	Listen         *string  `yaml:"listen" toml:"listen"`
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+}
+
+// CacheConfig is the search response cache settings
+type CacheConfig struct {
+	Backend    *string `yaml:"backend" toml:"backend"`
+	Size       *int    `yaml:"size" toml:"size"`
+	TTLNews    *string `yaml:"ttl_news" toml:"ttl_news"`
+	TTLGeneral *string `yaml:"ttl_general" toml:"ttl_general"`
+}
+
+// TavilyConfig is the tavily api settings
+type TavilyConfig struct {
+	BaseURL     *string `yaml:"base_url" toml:"base_url"`
+	Timeout     *string `yaml:"timeout" toml:"timeout"`
+	DialTimeout *string `yaml:"dial_timeout" toml:"dial_timeout"`
+	Proxy       *string `yaml:"proxy" toml:"proxy"`
+	CACert      *string `yaml:"ca_cert" toml:"ca_cert"`
+	Insecure    *bool   `yaml:"insecure" toml:"insecure"`
+	MaxRetries  *int    `yaml:"max_retries" toml:"max_retries"`
+}
+
+// ImageConfig is the image download settings
+type ImageConfig struct {
+	MaxBytes     *int64   `yaml:"max_bytes" toml:"max_bytes"`
+	MaxPixels    *int     `yaml:"max_pixels" toml:"max_pixels"`
+	BudgetBytes  *int     `yaml:"budget_bytes" toml:"budget_bytes"`
+	Format       *string  `yaml:"format" toml:"format"`
+	Quality      *int     `yaml:"quality" toml:"quality"`
+	Allow        []string `yaml:"allow" toml:"allow"`
+	Deny         []string `yaml:"deny" toml:"deny"`
+	MaxRedirects *int     `yaml:"max_redirects" toml:"max_redirects"`
+}
+
+// ToolConfig is the default arguments of a tool, used when the call omits them
+type ToolConfig struct {
+	Days        int    `yaml:"days" toml:"days"`
+	Limit       int    `yaml:"limit" toml:"limit"`
+	SearchDepth string `yaml:"search_depth" toml:"search_depth"`
+	Topic       string `yaml:"topic" toml:"topic"`
+}
+
+// LogConfig is the logging settings
+type LogConfig struct {
+	Debug      *bool    `yaml:"debug" toml:"debug"`
+	Level      *string  `yaml:"level" toml:"level"`
+	Format     *string  `yaml:"format" toml:"format"`
+	File       *string  `yaml:"file" toml:"file"`
+	MaxSize    *int     `yaml:"max_size" toml:"max_size"`
+	MaxBackups *int     `yaml:"max_backups" toml:"max_backups"`
+	MaxAge     *int     `yaml:"max_age" toml:"max_age"`
+	Redact     []string `yaml:"redact" toml:"redact"`
+}
+
+// MetricsConfig is the prometheus metrics settings
+type MetricsConfig struct {
+	Listen *string `yaml:"listen" toml:"listen"`
+}
+
+// TracingConfig is the opentelemetry tracing settings
+type TracingConfig struct {
+	Endpoint    *string  `yaml:"endpoint" toml:"endpoint"`
+	Protocol    *string  `yaml:"protocol" toml:"protocol"`
+	Insecure    *bool    `yaml:"insecure" toml:"insecure"`
+	Headers     []string `yaml:"headers" toml:"headers"`
+	SampleRatio *float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// BudgetConfig is the credit budget of each api key
+type BudgetConfig struct {
+	Daily   *int    `yaml:"daily" toml:"daily"`
+	Monthly *int    `yaml:"monthly" toml:"monthly"`
+	Policy  *string `yaml:"policy" toml:"policy"`
+	File    *string `yaml:"file" toml:"file"`
+}
+
+// RateLimitConfig is the limits of the tool calls of the server and of each client
+type RateLimitConfig struct {
+	Rate              *float64 `yaml:"rate" toml:"rate"`
+	Burst             *int     `yaml:"burst" toml:"burst"`
+	MaxInFlight       *int     `yaml:"max_in_flight" toml:"max_in_flight"`
+	ClientRate        *float64 `yaml:"client_rate" toml:"client_rate"`
+	ClientBurst       *int     `yaml:"client_burst" toml:"client_burst"`
+	ClientMaxInFlight *int     `yaml:"client_max_in_flight" toml:"client_max_in_flight"`
+	QueueTimeout      *string  `yaml:"queue_timeout" toml:"queue_timeout"`
+}
+
+// ConfigError is an error of the config file, Line is 0 if the position is unknown
+type ConfigError struct {
+	Line    int
+	Message string
+}
+
+// ConfigErrors is all the errors found in the config file
+type ConfigErrors struct {
+	Path   string
+	Errors []ConfigError
+}
+
+func (e *ConfigErrors) Error() string {
+	lines := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		if err.Line > 0 {
+			lines[i] = fmt.Sprintf("%s:%d: %s", e.Path, err.Line, err.Message)
+		} else {
+			lines[i] = fmt.Sprintf("%s: %s", e.Path, err.Message)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// configFlag is a config key and the run flag it sets
+type configFlag struct {
+	key   string
+	flag  string
+	value any
+}
+
+// flags return the config keys backed by the run flags
+func (c *Config) flags() []configFlag {
+	return []configFlag{
+		{"keys.strategy", "key-strategy", c.Keys.Strategy},
+		{"keys.cooldown", "key-cooldown", c.Keys.Cooldown},
+		{"transport.mode", "transport", c.Transport.Mode},
+		{"transport.listen", "listen", c.Transport.Listen},
+		{"transport.trusted_proxies", "trusted-proxies", c.Transport.TrustedProxies},
+		{"cache.backend", "cache", c.Cache.Backend},
+		{"cache.size", "cache-size", c.Cache.Size},
+		{"cache.ttl_news", "cache-ttl-news", c.Cache.TTLNews},
+		{"cache.ttl_general", "cache-ttl-general", c.Cache.TTLGeneral},
+		{"tavily.base_url", "base-url", c.Tavily.BaseURL},
+		{"tavily.timeout", "timeout", c.Tavily.Timeout},
+		{"tavily.dial_timeout", "dial-timeout", c.Tavily.DialTimeout},
+		{"tavily.proxy", "proxy", c.Tavily.Proxy},
+		{"tavily.ca_cert", "ca-cert", c.Tavily.CACert},
+		{"tavily.insecure", "insecure", c.Tavily.Insecure},
+		{"tavily.max_retries", "max-retries", c.Tavily.MaxRetries},
+		{"image.max_bytes", "image-max-bytes", c.Image.MaxBytes},
+		{"image.max_pixels", "image-max-pixels", c.Image.MaxPixels},
+		{"image.budget_bytes", "image-budget-bytes", c.Image.BudgetBytes},
+		{"image.format", "image-format", c.Image.Format},
+		{"image.quality", "image-quality", c.Image.Quality},
+		{"image.allow", "image-allow", c.Image.Allow},
+		{"image.deny", "image-deny", c.Image.Deny},
+		{"image.max_redirects", "image-max-redirects", c.Image.MaxRedirects},
+		{"log.debug", "debug", c.Log.Debug},
+		{"log.level", "log-level", c.Log.Level},
+		{"log.format", "log-format", c.Log.Format},
+		{"log.file", "log-file", c.Log.File},
+		{"log.max_size", "log-max-size", c.Log.MaxSize},
+		{"log.max_backups", "log-max-backups", c.Log.MaxBackups},
+		{"log.max_age", "log-max-age", c.Log.MaxAge},
+		{"log.redact", "log-redact", c.Log.Redact},
+		{"metrics.listen", "metrics-listen", c.Metrics.Listen},
+		{"tracing.endpoint", "otlp-endpoint", c.Tracing.Endpoint},
+		{"tracing.protocol", "otlp-protocol", c.Tracing.Protocol},
+		{"tracing.insecure", "otlp-insecure", c.Tracing.Insecure},
+		{"tracing.headers", "otlp-headers", c.Tracing.Headers},
+		{"tracing.sample_ratio", "trace-sample-ratio", c.Tracing.SampleRatio},
+		{"budget.daily", "budget-daily", c.Budget.Daily},
+		{"budget.monthly", "budget-monthly", c.Budget.Monthly},
+		{"budget.policy", "budget-policy", c.Budget.Policy},
+		{"budget.file", "budget-file", c.Budget.File},
+		{"rate_limit.rate", "rate-limit", c.RateLimit.Rate},
+		{"rate_limit.burst", "rate-burst", c.RateLimit.Burst},
+		{"rate_limit.max_in_flight", "max-in-flight", c.RateLimit.MaxInFlight},
+		{"rate_limit.client_rate", "client-rate-limit", c.RateLimit.ClientRate},
+		{"rate_limit.client_burst", "client-rate-burst", c.RateLimit.ClientBurst},
+		{"rate_limit.client_max_in_flight", "client-max-in-flight", c.RateLimit.ClientMaxInFlight},
+		{"rate_limit.queue_timeout", "rate-queue-timeout", c.RateLimit.QueueTimeout},
+	}
+}
+
+// flagValue format the config value as a flag value, false if the value is unset
+func flagValue(v any) (string, bool) {
+	switch v := v.(type) {
+	case *string:
+		if v != nil {
+			return *v, true
+		}
+	case *int:
+		if v != nil {
+			return strconv.Itoa(*v), true
+		}
+	case *int64:
+		if v != nil {
+			return strconv.FormatInt(*v, 10), true
+		}
+	case *float64:
+		if v != nil {
+			return strconv.FormatFloat(*v, 'f', -1, 64), true
+		}
+	case *bool:
+		if v != nil {
+			return strconv.FormatBool(*v), true
+		}
+	case []string:
+		if v != nil {
+			return strings.Join(v, ","), true
+		}
+	}
+	return "", false
+}
+
+// toolDefaults return the per-tool default arguments of the config
+func (c *Config) toolDefaults() map[string]tool.Defaults {
+	defaults := make(map[string]tool.Defaults, len(c.Tools))
+	for name, t := range c.Tools {
+		defaults[name] = tool.Defaults{
+			Days:        t.Days,
+			Limit:       t.Limit,
+			SearchDepth: t.SearchDepth,
+			Topic:       t.Topic,
+		}
+	}
+	return defaults
+}
+
+// LoadConfig read and validate the config file, the errors are *ConfigErrors with the line numbers
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		cfg   Config
+		lines map[string]int
+		errs  []ConfigError
+	)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		lines, errs = decodeYAML(data, &cfg)
+	case ".toml":
+		lines, errs = decodeTOML(data, &cfg)
+	default:
+		return nil, fmt.Errorf("config file %s is not supported, use .yaml, .yml or .toml", path)
+	}
+
+	// the decoded values are still checked, so all the errors are reported at once
+	errs = append(errs, cfg.validate(lines)...)
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool {
+			if errs[i].Line != errs[j].Line {
+				return errs[i].Line < errs[j].Line
+			}
+			return errs[i].Message < errs[j].Message
+		})
+		return nil, &ConfigErrors{Path: path, Errors: errs}
+	}
+	return &cfg, nil
+}
+
+// yamlLine match the line number in the yaml errors, like "yaml: line 3: ..." or "line 3: ..."
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// decodeYAML decode the yaml config, unknown keys are errors, it returns the lines of the keys
+func decodeYAML(data []byte, cfg *Config) (map[string]int, []ConfigError) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, []ConfigError{yamlError(err.Error())}
+	}
+	lines := map[string]int{}
+	if len(root.Content) > 0 {
+		yamlKeyLines(root.Content[0], "", lines)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return lines, []ConfigError{yamlError(err.Error())}
+		}
+		errs := make([]ConfigError, len(typeErr.Errors))
+		for i, msg := range typeErr.Errors {
+			errs[i] = yamlError(msg)
+		}
+		return lines, errs
+	}
+	return lines, nil
+}
+
+func yamlError(msg string) ConfigError {
+	if m := yamlLine.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return ConfigError{Line: line, Message: m[2]}
+	}
+	return ConfigError{Message: strings.TrimPrefix(msg, "yaml: ")}
+}
+
+// yamlKeyLines record the line of each key of the mapping node, nested keys are joined by dots
+func yamlKeyLines(node *yaml.Node, prefix string, lines map[string]int) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := prefix + node.Content[i].Value
+		lines[key] = node.Content[i].Line
+		yamlKeyLines(node.Content[i+1], key+".", lines)
+	}
+}
+
+// decodeTOML decode the toml config, unknown keys are errors, it returns the lines of the keys
+func decodeTOML(data []byte, cfg *Config) (map[string]int, []ConfigError) {
+	lines := tomlKeyLines(data)
+	md, err := toml.Decode(string(data), cfg)
+	if err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return lines, []ConfigError{{Line: parseErr.Position.Line, Message: parseErr.Message}}
+		}
+		return lines, []ConfigError{{Message: err.Error()}}
+	}
+	var errs []ConfigError
+	for _, key := range md.Undecoded() {
+		errs = append(errs, ConfigError{Line: lines[key.String()], Message: fmt.Sprintf("unknown key %s", key)})
+	}
+	return lines, errs
+}
+
+// tomlKey match the key of a key/value line, bare or quoted keys joined by dots
+var tomlKey = regexp.MustCompile(`^\s*((?:[A-Za-z0-9_-]+|"[^"]*")(?:\s*\.\s*(?:[A-Za-z0-9_-]+|"[^"]*"))*)\s*=`)
+
+// tomlKeyLines scan the toml lines for the table headers and the keys, nested keys are joined by dots
+func tomlKeyLines(data []byte) map[string]int {
+	lines := map[string]int{}
+	table := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			header := strings.Trim(line[:strings.LastIndex(line, "]")+1], "[]")
+			table = normalizeTOMLKey(header)
+			if _, ok := lines[table]; !ok {
+				lines[table] = n
+			}
+			continue
+		}
+		if m := tomlKey.FindStringSubmatch(line); m != nil {
+			key := normalizeTOMLKey(m[1])
+			if table != "" {
+				key = table + "." + key
+			}
+			lines[key] = n
+		}
+	}
+	return lines
+}
+
+func normalizeTOMLKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"`)
+	}
+	return strings.Join(parts, ".")
+}
+
+// validate check the values of the config, the errors carry the lines of the keys
+func (c *Config) validate(lines map[string]int) []ConfigError {
+	var errs []ConfigError
+	fail := func(key string, format string, args ...any) {
+		errs = append(errs, ConfigError{Line: lines[key], Message: key + ": " + fmt.Sprintf(format, args...)})
+	}
+	oneOf := func(key string, v *string, values ...string) {
+		if v == nil {
+			return
+		}
+		for _, value := range values {
+			if *v == value {
+				return
+			}
+		}
+		fail(key, "%q is not supported, use %s", *v, strings.Join(values, ", "))
+	}
+	duration := func(key string, v *string) {
+		if v == nil {
+			return
+		}
+		if d, err := time.ParseDuration(*v); err != nil || d < 0 {
+			fail(key, "%q is not a valid duration, like 10m or 1h", *v)
+		}
+	}
+	positive := func(key string, v *int) {
+		if v != nil && *v < 0 {
+			fail(key, "%d must not be negative", *v)
+		}
+	}
+
+	oneOf("keys.strategy", c.Keys.Strategy, tavily.KeyStrategyRoundRobin, tavily.KeyStrategyLeastUsed)
+	duration("keys.cooldown", c.Keys.Cooldown)
+	oneOf("transport.mode", c.Transport.Mode, TransportStdio, TransportHTTP)
+	if _, err := parseProxies(c.Transport.TrustedProxies); err != nil {
+		fail("transport.trusted_proxies", "%v", err)
+	}
+	oneOf("cache.backend", c.Cache.Backend, tavily.CacheNone, tavily.CacheMemory, tavily.CacheDisk)
+	positive("cache.size", c.Cache.Size)
+	duration("cache.ttl_news", c.Cache.TTLNews)
+	duration("cache.ttl_general", c.Cache.TTLGeneral)
+	if c.Tavily.BaseURL != nil {
+		if u, err := url.Parse(*c.Tavily.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("tavily.base_url", "%q is not a valid http(s) url", *c.Tavily.BaseURL)
+		}
+	}
+	duration("tavily.timeout", c.Tavily.Timeout)
+	duration("tavily.dial_timeout", c.Tavily.DialTimeout)
+	positive("tavily.max_retries", c.Tavily.MaxRetries)
+	if c.Image.MaxBytes != nil && *c.Image.MaxBytes < 0 {
+		fail("image.max_bytes", "%d must not be negative", *c.Image.MaxBytes)
+	}
+	positive("image.max_pixels", c.Image.MaxPixels)
+	positive("image.budget_bytes", c.Image.BudgetBytes)
+	oneOf("image.format", c.Image.Format, tool.ImageFormatOriginal, tool.ImageFormatJPEG, tool.ImageFormatPNG, tool.ImageFormatWebP)
+	if c.Image.Quality != nil && (*c.Image.Quality < 1 || *c.Image.Quality > 100) {
+		fail("image.quality", "%d must between 1 and 100", *c.Image.Quality)
+	}
+	positive("image.max_redirects", c.Image.MaxRedirects)
+	oneOf("log.level", c.Log.Level, "debug", "info", "warn", "error")
+	oneOf("log.format", c.Log.Format, logging.FormatText, logging.FormatJSON)
+	positive("log.max_size", c.Log.MaxSize)
+	positive("log.max_backups", c.Log.MaxBackups)
+	positive("log.max_age", c.Log.MaxAge)
+	positive("budget.daily", c.Budget.Daily)
+	positive("budget.monthly", c.Budget.Monthly)
+	oneOf("budget.policy", c.Budget.Policy, tavily.BudgetRefuse, tavily.BudgetDowngrade)
+	for key, v := range map[string]*float64{"rate_limit.rate": c.RateLimit.Rate, "rate_limit.client_rate": c.RateLimit.ClientRate} {
+		if v != nil && *v < 0 {
+			fail(key, "%v must not be negative", *v)
+		}
+	}
+	positive("rate_limit.burst", c.RateLimit.Burst)
+	positive("rate_limit.max_in_flight", c.RateLimit.MaxInFlight)
+	positive("rate_limit.client_burst", c.RateLimit.ClientBurst)
+	positive("rate_limit.client_max_in_flight", c.RateLimit.ClientMaxInFlight)
+	duration("rate_limit.queue_timeout", c.RateLimit.QueueTimeout)
+	oneOf("tracing.protocol", c.Tracing.Protocol, tracing.ProtocolGRPC, tracing.ProtocolHTTP)
+	if _, err := tracing.ParseHeaders(c.Tracing.Headers); err != nil {
+		fail("tracing.headers", "%v", err)
+	}
+	if c.Tracing.SampleRatio != nil && (*c.Tracing.SampleRatio < 0 || *c.Tracing.SampleRatio > 1) {
+		fail("tracing.sample_ratio", "%v must between 0 and 1", *c.Tracing.SampleRatio)
+	}
+
+	for name, t := range c.Tools {
+		key := "tools." + name
+		if err := tool.ValidateDefaults(name, tool.Defaults{}); err != nil {
+			fail(key, "%v", err)
+			continue
+		}
+		// check the fields one by one to report the line of each bad value
+		for field, d := range map[string]tool.Defaults{
+			"days":         {Days: t.Days},
+			"limit":        {Limit: t.Limit},
+			"search_depth": {SearchDepth: t.SearchDepth},
+			"topic":        {Topic: t.Topic},
+		} {
+			if err := tool.ValidateDefaults(name, d); err != nil {
+				fail(key+"."+field, "%v", errors.Unwrap(err))
+			}
+		}
+	}
+
+	return errs
+}
+
+// applyConfig set the flags not specified by the command line or the environment variables from the config
+func applyConfig(cmd *cobra.Command, cfg *Config) error {
+	for _, f := range cfg.flags() {
+		v, ok := flagValue(f.value)
+		if !ok || cmd.Flags().Changed(f.flag) {
+			continue
+		}
+		if err := cmd.Flags().Set(f.flag, v); err != nil {
+			return fmt.Errorf("invalid %s in config: %v", f.key, err)
+		}
+	}
+	return nil
+}
+
+// ConfigCmd
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the config file",
+}
+
+// ConfigValidateCmd
+var ConfigValidateCmd = &cobra.Command{
+	Use:   "validate <file>",
+	Short: "Validate the config file, errors are reported with the line numbers",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := LoadConfig(args[0]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s is valid\n", args[0])
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(ConfigCmd)
+	ConfigCmd.AddCommand(ConfigValidateCmd)
+}
@@ -1,70 +1,648 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/spf13/cobra"
-	"github.com/y7ut/mcp-tavily-search/internal/tavily"
+	"github.com/y7ut/mcp-tavily-search/internal/logging"
+	"github.com/y7ut/mcp-tavily-search/internal/metrics"
 	"github.com/y7ut/mcp-tavily-search/internal/tool"
+	"github.com/y7ut/mcp-tavily-search/internal/tracing"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
 )
 
-// debug flag
-var debug bool
+const (
+	TransportStdio = "stdio"
+	TransportHTTP  = "http"
+
+	// version is the version of the server
+	version = "1.0.0"
+
+	// shutdownTimeout is the max time to wait for the in-flight requests when the http server is stopping
+	shutdownTimeout = 10 * time.Second
+	// readHeaderTimeout is the max time to read the request headers, slow clients can not hold connections open
+	readHeaderTimeout = 10 * time.Second
+)
+
+var (
+	// config flag, the yaml or toml config file
+	configFile string
+	// debug flag, same as --log-level debug
+	debug bool
+	// log flags
+	logOptions = logging.DefaultOptions()
+	// metrics flag, the address /metrics listens on, empty disables it
+	metricsListen string
+	// tracing flags
+	traceOptions = tracing.DefaultOptions()
+	traceHeaders []string
+	// budget flags, caps of 0 disable the budget
+	budgetOptions = tavily.BudgetOptions{Policy: tavily.BudgetRefuse}
+	// rate limit flags, zero values disable the limits
+	rateLimitOptions = tool.RateLimitOptions{QueueTimeout: tool.DefaultQueueTimeout}
+	// transport flag, stdio or http
+	transport string
+	// listen flag, the address http transport listens on
+	listen string
+	// trusted proxies flag, the X-Forwarded-For header is only honored from them
+	trustedProxies []string
+	// cache flags
+	cacheBackend    string
+	cacheSize       int
+	cacheNewsTTL    time.Duration
+	cacheGeneralTTL time.Duration
+	// key pool flags
+	keyStrategy string
+	keyCooldown time.Duration
+	// retry flag
+	maxRetries int
+	// tavily http flags
+	baseURL     string
+	httpOptions tavily.HTTPOptions
+	// image flags
+	imageOptions = tool.DefaultImageOptions()
+	urlPolicy    = tool.DefaultURLPolicy()
+)
+
+// flagEnvs is the environment variables of the flags, flag takes precedence over env
+var flagEnvs = map[string]string{
+	"config":               "TRVILY_CONFIG",
+	"debug":                "TRVILY_DEBUG",
+	"log-level":            "TRVILY_LOG_LEVEL",
+	"log-format":           "TRVILY_LOG_FORMAT",
+	"log-file":             "TRVILY_LOG_FILE",
+	"log-max-size":         "TRVILY_LOG_MAX_SIZE",
+	"log-max-backups":      "TRVILY_LOG_MAX_BACKUPS",
+	"log-max-age":          "TRVILY_LOG_MAX_AGE",
+	"log-redact":           "TRVILY_LOG_REDACT",
+	"metrics-listen":       "TRVILY_METRICS_LISTEN",
+	"otlp-endpoint":        "TRVILY_OTLP_ENDPOINT",
+	"otlp-protocol":        "TRVILY_OTLP_PROTOCOL",
+	"otlp-insecure":        "TRVILY_OTLP_INSECURE",
+	"otlp-headers":         "TRVILY_OTLP_HEADERS",
+	"trace-sample-ratio":   "TRVILY_TRACE_SAMPLE_RATIO",
+	"budget-daily":         "TRVILY_BUDGET_DAILY",
+	"budget-monthly":       "TRVILY_BUDGET_MONTHLY",
+	"budget-policy":        "TRVILY_BUDGET_POLICY",
+	"budget-file":          "TRVILY_BUDGET_FILE",
+	"rate-limit":           "TRVILY_RATE_LIMIT",
+	"rate-burst":           "TRVILY_RATE_BURST",
+	"max-in-flight":        "TRVILY_MAX_IN_FLIGHT",
+	"client-rate-limit":    "TRVILY_CLIENT_RATE_LIMIT",
+	"client-rate-burst":    "TRVILY_CLIENT_RATE_BURST",
+	"client-max-in-flight": "TRVILY_CLIENT_MAX_IN_FLIGHT",
+	"rate-queue-timeout":   "TRVILY_RATE_QUEUE_TIMEOUT",
+	"transport":            "TRVILY_TRANSPORT",
+	"listen":               "TRVILY_LISTEN",
+	"trusted-proxies":      "TRVILY_TRUSTED_PROXIES",
+	"cache":                "TRVILY_CACHE",
+	"cache-size":           "TRVILY_CACHE_SIZE",
+	"cache-ttl-news":       "TRVILY_CACHE_TTL_NEWS",
+	"cache-ttl-general":    "TRVILY_CACHE_TTL_GENERAL",
+	"key-strategy":         "TRVILY_KEY_STRATEGY",
+	"key-cooldown":         "TRVILY_KEY_COOLDOWN",
+	"max-retries":          "TRVILY_MAX_RETRIES",
+	"base-url":             "TRVILY_BASE_URL",
+	"timeout":              "TRVILY_TIMEOUT",
+	"dial-timeout":         "TRVILY_DIAL_TIMEOUT",
+	"proxy":                "TRVILY_PROXY",
+	"ca-cert":              "TRVILY_CA_CERT",
+	"insecure":             "TRVILY_INSECURE",
+	"image-max-bytes":      "TRVILY_IMAGE_MAX_BYTES",
+	"image-max-pixels":     "TRVILY_IMAGE_MAX_PIXELS",
+	"image-budget-bytes":   "TRVILY_IMAGE_BUDGET_BYTES",
+	"image-format":         "TRVILY_IMAGE_FORMAT",
+	"image-quality":        "TRVILY_IMAGE_QUALITY",
+	"image-allow":          "TRVILY_IMAGE_ALLOW",
+	"image-deny":           "TRVILY_IMAGE_DENY",
+	"image-max-redirects":  "TRVILY_IMAGE_MAX_REDIRECTS",
+}
 
 // RunCmd
-// environment variables:
-// TRVILY_API_KEY = "your tavily api key"
+// environment variables, they take precedence over the config file:
+// TRVILY_CONFIG = "/path/to/config.yaml" or "/path/to/config.toml"
+// TRVILY_API_KEY = "your tavily api key", or "key1,key2" for a key pool
 // TRVILY_INCLUDE_DOMAINS = "domain1,domain2"
 // TRVILY_EXCLUDE_DOMAINS = "domain1,domain2"
+// TRVILY_KEY_STRATEGY = "round-robin" or "least-used"
+// TRVILY_KEY_COOLDOWN = "10m"
+// TRVILY_TRANSPORT = "stdio" or "http"
+// TRVILY_LISTEN = ":8080"
+// TRVILY_CACHE = "none", "memory" or "disk"
+// TRVILY_CACHE_SIZE = "256"
+// TRVILY_CACHE_TTL_NEWS = "5m"
+// TRVILY_CACHE_TTL_GENERAL = "1h"
+// TRVILY_MAX_RETRIES = "3"
+// TRVILY_BASE_URL = "https://api.tavily.com"
+// TRVILY_TIMEOUT = "60s"
+// TRVILY_DIAL_TIMEOUT = "10s"
+// TRVILY_PROXY = "http://proxy:3128"
+// TRVILY_CA_CERT = "/path/to/ca.pem"
+// TRVILY_INSECURE = "false"
+// TRVILY_IMAGE_MAX_BYTES = "5242880"
+// TRVILY_IMAGE_MAX_PIXELS = "1048576"
+// TRVILY_IMAGE_BUDGET_BYTES = "1048576"
+// TRVILY_IMAGE_FORMAT = "jpeg", "png" or "webp"
+// TRVILY_IMAGE_QUALITY = "85"
+// TRVILY_IMAGE_ALLOW = "cdn.internal,.example.com,10.0.0.0/8"
+// TRVILY_IMAGE_DENY = "evil.com,203.0.113.0/24"
+// TRVILY_IMAGE_MAX_REDIRECTS = "3"
+// TRVILY_DEBUG = "false"
+// TRVILY_LOG_LEVEL = "debug", "info", "warn" or "error"
+// TRVILY_LOG_FORMAT = "text" or "json"
+// TRVILY_LOG_FILE = "/var/log/mcp-tavily-search.log"
+// TRVILY_LOG_MAX_SIZE = "100"
+// TRVILY_LOG_MAX_BACKUPS = "3"
+// TRVILY_LOG_MAX_AGE = "28"
+// TRVILY_LOG_REDACT = "query,question"
+// TRVILY_METRICS_LISTEN = ":9090"
+// TRVILY_OTLP_ENDPOINT = "localhost:4317"
+// TRVILY_OTLP_PROTOCOL = "grpc" or "http"
+// TRVILY_OTLP_INSECURE = "false"
+// TRVILY_OTLP_HEADERS = "authorization=Bearer xxx"
+// TRVILY_TRACE_SAMPLE_RATIO = "1"
+// TRVILY_BUDGET_DAILY = "100"
+// TRVILY_BUDGET_MONTHLY = "1000"
+// TRVILY_BUDGET_POLICY = "refuse" or "downgrade"
+// TRVILY_BUDGET_FILE = "/path/to/budget.json"
+// TRVILY_RATE_LIMIT = "10"
+// TRVILY_RATE_BURST = "20"
+// TRVILY_MAX_IN_FLIGHT = "16"
+// TRVILY_CLIENT_RATE_LIMIT = "1"
+// TRVILY_CLIENT_RATE_BURST = "5"
+// TRVILY_CLIENT_MAX_IN_FLIGHT = "4"
+// TRVILY_RATE_QUEUE_TIMEOUT = "10s"
 var RunCmd = &cobra.Command{
-	Use:   "run",
+	Use:   "run [api key...]",
 	Short: "Run the server",
 	Run: func(cmd *cobra.Command, args []string) {
-		trvilyApiKey, _ := os.LookupEnv("TRVILY_API_KEY")
+		if err := bindEnvs(cmd); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		cfg := &Config{}
+		if configFile != "" {
+			var err error
+			if cfg, err = LoadConfig(configFile); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if err := applyConfig(cmd, cfg); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		// api keys: args > env > config
+		trvilyApiKeys := cfg.APIKeys
+		if keys := splitList(os.Getenv("TRVILY_API_KEY")); len(keys) > 0 {
+			trvilyApiKeys = keys
+		}
 		if len(args) > 0 {
-			trvilyApiKey = args[0]
+			trvilyApiKeys = nil
+			for _, arg := range args {
+				trvilyApiKeys = append(trvilyApiKeys, splitList(arg)...)
+			}
 		}
-		if trvilyApiKey == "" {
+		if len(trvilyApiKeys) == 0 {
 			fmt.Println("TRVILY_API_KEY is required")
 			os.Exit(1)
 		}
-		var includeDomain []string
-		if os.Getenv("TRVILY_INCLUDE_DOMAINS") != "" {
-			includeDomain = strings.Split(os.Getenv("TRVILY_INCLUDE_DOMAINS"), ",")
+		includeDomain := cfg.IncludeDomains
+		if domains := splitList(os.Getenv("TRVILY_INCLUDE_DOMAINS")); len(domains) > 0 {
+			includeDomain = domains
+		}
+		excludeDomain := cfg.ExcludeDomains
+		if domains := splitList(os.Getenv("TRVILY_EXCLUDE_DOMAINS")); len(domains) > 0 {
+			excludeDomain = domains
+		}
+
+		if transport != TransportStdio && transport != TransportHTTP {
+			fmt.Printf("transport %s is not supported, use %s or %s\n", transport, TransportStdio, TransportHTTP)
+			os.Exit(1)
+		}
+
+		proxies, err := parseProxies(trustedProxies)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		keys, err := tavily.NewKeyPool(trvilyApiKeys, keyStrategy, keyCooldown)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
 
-		var excludeDomain []string
-		if os.Getenv("TRVILY_EXCLUDE_DOMAINS") != "" {
-			excludeDomain = strings.Split(os.Getenv("TRVILY_EXCLUDE_DOMAINS"), ",")
+		tools, err := tool.NewTools(tool.Options{
+			Defaults:  cfg.toolDefaults(),
+			Image:     imageOptions,
+			URLPolicy: urlPolicy,
+		})
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
 
-		tavily.Init(trvilyApiKey, debug, includeDomain, excludeDomain)
-		mcpServerRun()
+		cache, err := newCache(cacheBackend, cacheSize)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if debug {
+			logOptions.Level = slog.LevelDebug.String()
+		}
+		logger, logOut, err := logging.New(logOptions)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer logging.Close(logOut)
+		slog.SetDefault(logger)
+
+		clientOptions := []tavily.ClientOption{
+			tavily.WithKeyPool(keys),
+			tavily.WithDomainPolicy(includeDomain, excludeDomain),
+			tavily.WithBaseURL(baseURL),
+			tavily.WithHTTPOptions(httpOptions),
+			tavily.WithRetry(maxRetries, tavily.DefaultRetryBaseDelay, tavily.DefaultRetryMaxDelay),
+			tavily.WithLogger(logger),
+			tavily.WithCache(cache, tavily.CacheTTL{
+				tavily.TopicNews:    cacheNewsTTL,
+				tavily.TopicGeneral: cacheGeneralTTL,
+			}),
+		}
+		if err := rateLimitOptions.Validate(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		budget, err := newBudget(budgetOptions)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if budget != nil {
+			clientOptions = append(clientOptions, tavily.WithBudget(budget))
+			defer func() {
+				if err := budget.Flush(); err != nil {
+					logger.Error("budget save error", "error", err)
+				}
+			}()
+		}
+		var m *metrics.Metrics
+		if metricsListen != "" {
+			m = metrics.New()
+			clientOptions = append(clientOptions, tavily.WithMetrics(m))
+		}
+		if traceOptions.Headers, err = tracing.ParseHeaders(traceHeaders); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		tp, shutdownTracing, err := tracing.New(context.Background(), version, traceOptions)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				logger.Error("tracing shutdown error", "error", err)
+			}
+		}()
+		clientOptions = append(clientOptions, tavily.WithTracerProvider(tp))
+
+		client, err := tavily.NewClient(clientOptions...)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		middlewares := []server.ToolHandlerMiddleware{
+			tool.TracingMiddleware(tp),
+			tool.LoggingMiddleware(logger),
+		}
+		if m != nil {
+			middlewares = append(middlewares, tool.MetricsMiddleware(m))
+		}
+		if rateLimitOptions.Enabled() {
+			// inside the logging and metrics, so the rejected calls are logged and counted
+			middlewares = append(middlewares, tool.RateLimitMiddleware(rateLimitOptions))
+		}
+		mcpServerRun(NewMCPServer(client, tools, middlewares...), proxies, logger, m)
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(RunCmd)
 
-	RunCmd.Flags().BoolVarP(&debug, "debug", "d", true, "Enable debug mode")
+	RunCmd.Flags().StringVarP(&configFile, "config", "c", "", "Yaml or toml config file, flags and environment variables take precedence over it")
+	RunCmd.Flags().BoolVarP(&debug, "debug", "d", false, "Enable debug logging, same as --log-level debug")
+	RunCmd.Flags().StringVarP(&transport, "transport", "t", TransportStdio, "Transport of the server, stdio or http (serves both streamable http on /mcp and sse on /sse)")
+	RunCmd.Flags().StringVarP(&listen, "listen", "l", ":8080", "Address the http transport listens on")
+	RunCmd.Flags().StringSliceVar(&trustedProxies, "trusted-proxies", nil, "Ips and cidrs of the reverse proxies whose X-Forwarded-For header gives the client address, the header is ignored from others")
+	RunCmd.Flags().StringVar(&cacheBackend, "cache", tavily.CacheMemory, "Cache backend of search responses, none, memory or disk (~/.mcp-tavily-search/cache)")
+	RunCmd.Flags().IntVar(&cacheSize, "cache-size", tavily.DefaultCacheSize, "Max entries of the memory or disk cache")
+	RunCmd.Flags().DurationVar(&cacheNewsTTL, "cache-ttl-news", tavily.DefaultNewsCacheTTL, "TTL of cached news search responses, 0 disables it")
+	RunCmd.Flags().DurationVar(&cacheGeneralTTL, "cache-ttl-general", tavily.DefaultGeneralCacheTTL, "TTL of cached general search responses, 0 disables it")
+	RunCmd.Flags().StringVar(&keyStrategy, "key-strategy", tavily.KeyStrategyRoundRobin, "Rotation strategy of the api keys, round-robin or least-used")
+	RunCmd.Flags().DurationVar(&keyCooldown, "key-cooldown", tavily.DefaultKeyCooldown, "Cool-down period of the api key failed with auth or quota errors")
+	RunCmd.Flags().IntVar(&maxRetries, "max-retries", tavily.DefaultMaxRetries, "Max retries of the tavily request failed with network errors, 429 or 5xx")
+	RunCmd.Flags().StringVar(&baseURL, "base-url", tavily.DefaultBaseURL, "Base url of the tavily api, point it to a local fake for tests")
+	RunCmd.Flags().DurationVar(&httpOptions.Timeout, "timeout", tavily.DefaultTimeout, "Timeout of each tavily request")
+	RunCmd.Flags().DurationVar(&httpOptions.DialTimeout, "dial-timeout", tavily.DefaultDialTimeout, "Timeout of connecting to tavily, including the tls handshake")
+	RunCmd.Flags().StringVar(&httpOptions.Proxy, "proxy", "", "Proxy url of the tavily requests, default is from HTTP_PROXY/HTTPS_PROXY")
+	RunCmd.Flags().StringVar(&httpOptions.CACertFile, "ca-cert", "", "Pem file of extra ca certs to trust, like the egress proxy ca")
+	RunCmd.Flags().BoolVar(&httpOptions.InsecureSkipVerify, "insecure", false, "Skip the tls certificate verification of tavily, for local fakes only")
+	RunCmd.Flags().Int64Var(&imageOptions.MaxBytes, "image-max-bytes", imageOptions.MaxBytes, "Max size of the downloaded image, larger images are rejected")
+	RunCmd.Flags().IntVar(&imageOptions.MaxPixels, "image-max-pixels", imageOptions.MaxPixels, "Pixel budget of the returned image, larger images are downscaled, 0 means no limit")
+	RunCmd.Flags().IntVar(&imageOptions.BudgetBytes, "image-budget-bytes", imageOptions.BudgetBytes, "Byte budget of the returned image, larger images are re-encoded and downscaled, 0 means no limit")
+	RunCmd.Flags().StringVar(&imageOptions.Format, "image-format", imageOptions.Format, "Format images are re-encoded to, jpeg, png or webp, empty keeps the original format")
+	RunCmd.Flags().IntVar(&imageOptions.Quality, "image-quality", imageOptions.Quality, "Jpeg quality of the re-encoded image")
+	RunCmd.Flags().StringSliceVar(&urlPolicy.Allow, "image-allow", nil, "Hosts and cidrs images can be fetched from even if they are private, .example.com matches the subdomains")
+	RunCmd.Flags().StringSliceVar(&urlPolicy.Deny, "image-deny", nil, "Hosts and cidrs images are never fetched from")
+	RunCmd.Flags().IntVar(&urlPolicy.MaxRedirects, "image-max-redirects", urlPolicy.MaxRedirects, "Max redirects followed when downloading an image")
+	RunCmd.Flags().StringVar(&logOptions.Level, "log-level", logOptions.Level, "Log level, debug, info, warn or error")
+	RunCmd.Flags().StringVar(&logOptions.Format, "log-format", logOptions.Format, "Log format, text or json")
+	RunCmd.Flags().StringVar(&logOptions.File, "log-file", "", "Log file rotated by size, default is stderr")
+	RunCmd.Flags().IntVar(&logOptions.MaxSize, "log-max-size", logOptions.MaxSize, "Max megabytes of the log file before it is rotated")
+	RunCmd.Flags().IntVar(&logOptions.MaxBackups, "log-max-backups", logOptions.MaxBackups, "Max rotated log files kept, 0 keeps all")
+	RunCmd.Flags().IntVar(&logOptions.MaxAge, "log-max-age", logOptions.MaxAge, "Max days rotated log files are kept, 0 keeps them forever")
+	RunCmd.Flags().StringSliceVar(&logOptions.Redact, "log-redact", nil, "Fields redacted from the logs besides the api keys, like query")
+	RunCmd.Flags().StringVar(&metricsListen, "metrics-listen", "", "Address the prometheus /metrics endpoint listens on, like :9090, empty disables it")
+	RunCmd.Flags().StringVar(&traceOptions.Endpoint, "otlp-endpoint", "", "Host:port of the otlp collector the traces are exported to, empty disables tracing")
+	RunCmd.Flags().StringVar(&traceOptions.Protocol, "otlp-protocol", traceOptions.Protocol, "Protocol of the otlp exporter, grpc or http")
+	RunCmd.Flags().BoolVar(&traceOptions.Insecure, "otlp-insecure", false, "Export the traces without tls")
+	RunCmd.Flags().StringSliceVar(&traceHeaders, "otlp-headers", nil, "Headers of the otlp exports, key=value")
+	RunCmd.Flags().IntVar(&budgetOptions.Daily, "budget-daily", 0, "Max estimated tavily credits each api key spends per day (UTC), 0 means no cap")
+	RunCmd.Flags().IntVar(&budgetOptions.Monthly, "budget-monthly", 0, "Max estimated tavily credits each api key spends per month (UTC), 0 means no cap")
+	RunCmd.Flags().StringVar(&budgetOptions.Policy, "budget-policy", budgetOptions.Policy, "What to do with the calls exceeding the budget, refuse, or downgrade advanced searches to basic")
+	RunCmd.Flags().StringVar(&budgetOptions.File, "budget-file", "", "File persisting the credits spent, default is ~/.mcp-tavily-search/budget.json")
+	RunCmd.Flags().Float64Var(&rateLimitOptions.Global.Rate, "rate-limit", 0, "Tool calls per second of the server, 0 means no limit")
+	RunCmd.Flags().IntVar(&rateLimitOptions.Global.Burst, "rate-burst", 0, "Burst of the tool calls of the server, default is the rate limit")
+	RunCmd.Flags().IntVar(&rateLimitOptions.Global.MaxInFlight, "max-in-flight", 0, "Max tool calls of the server running at the same time, 0 means no limit")
+	RunCmd.Flags().Float64Var(&rateLimitOptions.PerClient.Rate, "client-rate-limit", 0, "Tool calls per second of each client or session, 0 means no limit")
+	RunCmd.Flags().IntVar(&rateLimitOptions.PerClient.Burst, "client-rate-burst", 0, "Burst of the tool calls of each client, default is the client rate limit")
+	RunCmd.Flags().IntVar(&rateLimitOptions.PerClient.MaxInFlight, "client-max-in-flight", 0, "Max tool calls of each client running at the same time, 0 means no limit")
+	RunCmd.Flags().DurationVar(&rateLimitOptions.QueueTimeout, "rate-queue-timeout", rateLimitOptions.QueueTimeout, "Max time a call over the limits waits before it is rejected")
+	RunCmd.Flags().Float64Var(&traceOptions.SampleRatio, "trace-sample-ratio", traceOptions.SampleRatio, "Ratio of the tool calls traced, calls with a sampled traceparent in _meta are always traced")
 }
 
-// mcpServerRun run the mcp server
-func mcpServerRun() {
-	// Create MCP server
-	s := server.NewMCPServer(
-		"MCP Tavily Search 🔍",
-		"1.0.0",
+// splitList split the comma separated list, empty items are dropped
+func splitList(s string) []string {
+	var res []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+// bindEnvs set the flags from their environment variables when the flags are not specified
+func bindEnvs(cmd *cobra.Command) error {
+	for flag, env := range flagEnvs {
+		if cmd.Flags().Changed(flag) {
+			continue
+		}
+		if v, ok := os.LookupEnv(env); ok && v != "" {
+			if err := cmd.Flags().Set(flag, v); err != nil {
+				return fmt.Errorf("invalid %s: %v", env, err)
+			}
+		}
+	}
+	return nil
+}
+
+// newCache create the cache of search responses by backend, nil means cache is disabled
+func newCache(backend string, size int) (tavily.Cache, error) {
+	switch backend {
+	case tavily.CacheNone, "":
+		return nil, nil
+	case tavily.CacheMemory:
+		return tavily.NewMemoryCache(size), nil
+	case tavily.CacheDisk:
+		toolPath, err := ToolPath()
+		if err != nil {
+			return nil, err
+		}
+		return tavily.NewDiskCache(filepath.Join(toolPath, "cache"), size)
+	default:
+		return nil, fmt.Errorf("cache %s is not supported, use %s, %s or %s", backend, tavily.CacheNone, tavily.CacheMemory, tavily.CacheDisk)
+	}
+}
+
+// newBudget create the credit budget of the api keys persisted to ~/.mcp-tavily-search/budget.json by default,
+// nil if no cap is set
+func newBudget(opts tavily.BudgetOptions) (*tavily.Budget, error) {
+	if opts.Daily == 0 && opts.Monthly == 0 {
+		return nil, nil
+	}
+	if opts.File == "" {
+		toolPath, err := ToolPath()
+		if err != nil {
+			return nil, err
+		}
+		opts.File = filepath.Join(toolPath, "budget.json")
+	}
+	return tavily.NewBudget(opts)
+}
+
+// ToolPath return the work dir of the tool, ~/.mcp-tavily-search, it is created if not exists
+func ToolPath() (string, error) {
+	userHomeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user dir: %v", err)
+	}
+
+	toolPath := fmt.Sprintf("%s/.mcp-tavily-search", userHomeDir)
+	if _, err := os.Stat(toolPath); os.IsNotExist(err) {
+		err := os.Mkdir(toolPath, os.ModePerm)
+		if err != nil {
+			return "", fmt.Errorf("failed to create %s: %v", toolPath, err)
+		}
+	}
+	return toolPath, nil
+}
+
+// NewMCPServer create the mcp server with the tavily tools bound to the client and their settings,
+// the middlewares wrap each tool call, the first one is the outermost
+func NewMCPServer(client tool.TavilyClient, tools *tool.Tools, middlewares ...server.ToolHandlerMiddleware) *server.MCPServer {
+	opts := []server.ServerOption{
 		server.WithLogging(),
 		server.WithResourceCapabilities(true, true),
+	}
+	for _, middleware := range middlewares {
+		opts = append(opts, server.WithToolHandlerMiddleware(middleware))
+	}
+	s := server.NewMCPServer(
+		"MCP Tavily Search 🔍",
+		version,
+		opts...,
 	)
 
-	tool.Bind(s)
+	tool.Bind(s, client, tools)
+	return s
+}
+
+// mcpServerRun run the mcp server
+func mcpServerRun(s *server.MCPServer, proxies []netip.Prefix, logger *slog.Logger, metrics *metrics.Metrics) {
+	if metrics != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			logger.Info("metrics server listening", "addr", metricsListen, "path", "/metrics")
+			if err := metrics.Serve(ctx, metricsListen); err != nil {
+				logger.Error("metrics server error", "error", err)
+			}
+		}()
+	}
+
+	if transport == TransportHTTP {
+		if err := serveHTTP(s, listen, proxies, logger); err != nil {
+			logger.Error("server error", "error", err)
+		}
+		return
+	}
+
 	// Start the stdio server
-	if err := server.ServeStdio(s); err != nil {
-		fmt.Printf("Server error: %v\n", err)
+	if err := server.ServeStdio(s, server.WithErrorLogger(slog.NewLogLogger(logger.Handler(), slog.LevelError))); err != nil {
+		logger.Error("server error", "error", err)
+	}
+}
+
+// serveHTTP serve the mcp server over streamable http and sse on addr, until SIGINT or SIGTERM received,
+// the X-Forwarded-For header is honored from the trusted proxies only
+func serveHTTP(s *server.MCPServer, addr string, proxies []netip.Prefix, logger *slog.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	mux := http.NewServeMux()
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	streamableServer := server.NewStreamableHTTPServer(s,
+		server.WithStreamableHTTPServer(httpServer),
+		server.WithHTTPContextFunc(clientContext(proxies)),
+	)
+	sseServer := server.NewSSEServer(s,
+		server.WithHTTPServer(httpServer),
+		server.WithSSEContextFunc(clientContext(proxies)),
+	)
+	mux.Handle("/mcp", streamableServer)
+	mux.Handle(sseServer.CompleteSsePath(), sseServer)
+	mux.Handle(sseServer.CompleteMessagePath(), sseServer)
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("mcp server listening", "addr", addr, "streamable_http", "/mcp", "sse", sseServer.CompleteSsePath())
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("mcp server shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	// sse server shutdown closes all sse sessions first, so the long-lived streams do not hold the shutdown,
+	// then both servers shut down the shared http server
+	if err := sseServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := streamableServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// clientContext give each http request its own context carrying the address of the client
+func clientContext(proxies []netip.Prefix) func(ctx context.Context, r *http.Request) context.Context {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		return tool.WithClientAddr(ctx, clientAddr(r, proxies))
+	}
+}
+
+// clientAddr return the address of the client of the request. behind the trusted proxies, the X-Forwarded-For header
+// is walked from the nearest hop, and the first address which is not a trusted proxy is the client,
+// the header is ignored when the peer is not a trusted proxy since anyone can set it
+func clientAddr(r *http.Request, proxies []netip.Prefix) string {
+	addr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		addr = host
+	}
+	trusted := func(s string) bool {
+		ip, err := netip.ParseAddr(s)
+		if err != nil {
+			return false
+		}
+		for _, prefix := range proxies {
+			if prefix.Contains(ip.Unmap()) {
+				return true
+			}
+		}
+		return false
+	}
+	if !trusted(addr) {
+		return addr
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			// a malformed hop can not be trusted, the last proxy seen is the client
+			return addr
+		}
+		if !trusted(hop) {
+			return hop
+		}
+		addr = hop
+	}
+	return addr
+}
+
+// parseProxies parse the ips and cidrs of the trusted proxies
+func parseProxies(list []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %s is not a valid cidr", item)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		ip, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %s is not a valid ip", item)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(ip.Unmap(), ip.Unmap().BitLen()))
 	}
+	return prefixes, nil
 }
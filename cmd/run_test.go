@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientAddr(t *testing.T) {
+	proxies, err := parseProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{name: "direct", remote: "203.0.113.7:51234", want: "203.0.113.7"},
+		{name: "spoofed header from a client", remote: "203.0.113.7:51234", xff: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "behind a trusted proxy", remote: "10.1.2.3:443", xff: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "spoofed hop before the proxy", remote: "10.1.2.3:443", xff: []string{"1.2.3.4, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "chain of trusted proxies", remote: "10.1.2.3:443", xff: []string{"198.51.100.1, 192.168.1.1", "10.9.9.9"}, want: "198.51.100.1"},
+		{name: "malformed hop", remote: "10.1.2.3:443", xff: []string{"not-an-ip, 10.9.9.9"}, want: "10.9.9.9"},
+		{name: "no header", remote: "10.1.2.3:443", want: "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/mcp", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := clientAddr(r, proxies); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProxies(t *testing.T) {
+	for _, list := range [][]string{{"10.0.0.0/33"}, {"proxy.local"}} {
+		if _, err := parseProxies(list); err == nil {
+			t.Errorf("parseProxies(%v) got no error", list)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Package logging builds the slog logger of the server, writing text or json to stderr or a rotating file,
+// with the api keys and the configured fields redacted.
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+
+	DefaultMaxSize    = 100
+	DefaultMaxBackups = 3
+	DefaultMaxAge     = 28
+
+	// Redacted replace the values of the redacted fields
+	Redacted = "[REDACTED]"
+)
+
+// DefaultRedact is the fields always redacted, matched case-insensitively at any depth
+var DefaultRedact = []string{"api_key", "apikey", "authorization", "password", "secret", "token", "proxy"}
+
+// apiKeyPattern match the tavily api keys leaked into messages and values
+var apiKeyPattern = regexp.MustCompile(`tvly-[A-Za-z0-9_-]+`)
+
+// Options is the settings of the logger
+type Options struct {
+	// Level is debug, info, warn or error
+	Level string
+	// Format is text or json
+	Format string
+	// File is the log file rotated by size, empty writes to stderr
+	File string
+	// MaxSize is the max megabytes of the log file before it is rotated
+	MaxSize int
+	// MaxBackups is the max rotated files kept
+	MaxBackups int
+	// MaxAge is the max days the rotated files are kept
+	MaxAge int
+	// Redact is the fields redacted besides DefaultRedact
+	Redact []string
+}
+
+// DefaultOptions
+func DefaultOptions() Options {
+	return Options{
+		Level:      slog.LevelInfo.String(),
+		Format:     FormatText,
+		MaxSize:    DefaultMaxSize,
+		MaxBackups: DefaultMaxBackups,
+		MaxAge:     DefaultMaxAge,
+	}
+}
+
+// New create the logger by the options, close the returned closer when done to flush the log file
+func New(opts Options) (*slog.Logger, io.Closer, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+		return nil, nil, fmt.Errorf("log level %s is not supported, use debug, info, warn or error", opts.Level)
+	}
+
+	var out io.WriteCloser = nopCloser{os.Stderr}
+	if opts.File != "" {
+		out = &lumberjack.Logger{
+			Filename:   opts.File,
+			MaxSize:    opts.MaxSize,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAge,
+		}
+	}
+
+	handlerOpts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: newRedactor(opts.Redact).replaceAttr,
+	}
+	var handler slog.Handler
+	switch opts.Format {
+	case FormatText, "":
+		handler = slog.NewTextHandler(out, handlerOpts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	default:
+		out.Close()
+		return nil, nil, fmt.Errorf("log format %s is not supported, use %s or %s", opts.Format, FormatText, FormatJSON)
+	}
+	return slog.New(handler), out, nil
+}
+
+// Discard return a logger dropping every record
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// redactor redact the fields by name and mask the api keys in the values
+type redactor struct {
+	fields map[string]bool
+}
+
+func newRedactor(fields []string) *redactor {
+	r := &redactor{fields: map[string]bool{}}
+	for _, field := range append(append([]string{}, DefaultRedact...), fields...) {
+		if field = strings.ToLower(strings.TrimSpace(field)); field != "" {
+			r.fields[field] = true
+		}
+	}
+	return r
+}
+
+func (r *redactor) replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if r.fields[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, maskKeys(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			return slog.String(a.Key, maskKeys(v.Error()))
+		case map[string]any:
+			return slog.Any(a.Key, r.redactMap(v))
+		case []byte:
+			return slog.String(a.Key, maskKeys(string(v)))
+		}
+	}
+	return a
+}
+
+// redactMap return a copy of the map with the fields redacted, nested maps and slices are walked
+func (r *redactor) redactMap(m map[string]any) map[string]any {
+	res := make(map[string]any, len(m))
+	for k, v := range m {
+		if r.fields[strings.ToLower(k)] {
+			res[k] = Redacted
+			continue
+		}
+		res[k] = r.redactValue(v)
+	}
+	return res
+}
+
+func (r *redactor) redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return r.redactMap(v)
+	case []any:
+		res := make([]any, len(v))
+		for i, item := range v {
+			res[i] = r.redactValue(item)
+		}
+		return res
+	case string:
+		return maskKeys(v)
+	default:
+		return v
+	}
+}
+
+// maskKeys mask the tavily api keys in s
+func maskKeys(s string) string {
+	return apiKeyPattern.ReplaceAllStringFunc(s, tavily.MaskKey)
+}
+
+// Close close the closer returned by New, errors of closing stderr are ignored
+func Close(c io.Closer) error {
+	if c == nil {
+		return nil
+	}
+	if err := c.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	return nil
+}
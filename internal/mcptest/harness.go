@@ -0,0 +1,149 @@
+// Package mcptest drives the mcp server of the run command through an in-process client,
+// with the tavily api replaced by the fake of tavilytest, so tools can be tested without network access.
+package mcptest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/y7ut/mcp-tavily-search/cmd"
+	"github.com/y7ut/mcp-tavily-search/internal/tool"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily/tavilytest"
+)
+
+// Harness is an in-process mcp client connected to the server, backed by the fake tavily api
+type Harness struct {
+	Fake   *tavilytest.Server
+	Tavily *tavily.Client
+	Client *client.Client
+}
+
+// Option customize the tavily client before the server starts
+type Option func(*tavily.Client)
+
+// WithCache enable the response cache
+func WithCache(cache tavily.Cache, ttl tavily.CacheTTL) Option {
+	return func(t *tavily.Client) {
+		t.SetCache(cache, ttl)
+	}
+}
+
+// WithDomains set the server-wide domain policy
+func WithDomains(include, exclude []string) Option {
+	return func(t *tavily.Client) {
+		t.IncludeDomains = include
+		t.ExcludeDomains = exclude
+	}
+}
+
+// New start the fake tavily api and the mcp server, then initialize the client
+func New(ctx context.Context, opts ...Option) (*Harness, error) {
+	fake := tavilytest.NewServer()
+	t := fake.Client()
+	for _, opt := range opts {
+		opt(t)
+	}
+	// the fake serves images on loopback
+	toolOptions := tool.DefaultOptions()
+	toolOptions.URLPolicy.Allow = []string{"127.0.0.1"}
+	tools, err := tool.NewTools(toolOptions)
+	if err != nil {
+		fake.Close()
+		return nil, err
+	}
+
+	c, err := client.NewInProcessClient(cmd.NewMCPServer(t, tools))
+	if err != nil {
+		fake.Close()
+		return nil, fmt.Errorf("mcptest: failed to create client: %v", err)
+	}
+	if err := c.Start(ctx); err != nil {
+		fake.Close()
+		return nil, fmt.Errorf("mcptest: failed to start client: %v", err)
+	}
+
+	initReq := mcp.InitializeRequest{}
+	initReq.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initReq.Params.ClientInfo = mcp.Implementation{Name: "mcptest", Version: "1.0.0"}
+	if _, err := c.Initialize(ctx, initReq); err != nil {
+		c.Close()
+		fake.Close()
+		return nil, fmt.Errorf("mcptest: failed to initialize: %v", err)
+	}
+
+	return &Harness{Fake: fake, Tavily: t, Client: c}, nil
+}
+
+// ListTools return the tools registered by the server
+func (h *Harness) ListTools(ctx context.Context) ([]mcp.Tool, error) {
+	res, err := h.Client.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return res.Tools, nil
+}
+
+// Tool return the tool by name
+func (h *Harness) Tool(ctx context.Context, name string) (*mcp.Tool, error) {
+	tools, err := h.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tools {
+		if tools[i].Name == name {
+			return &tools[i], nil
+		}
+	}
+	return nil, fmt.Errorf("mcptest: tool %s is not registered", name)
+}
+
+// CallTool call the tool with the arguments
+func (h *Harness) CallTool(ctx context.Context, name string, args map[string]any) (*mcp.CallToolResult, error) {
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+	return h.Client.CallTool(ctx, req)
+}
+
+// ReadResource read the resource by uri
+func (h *Harness) ReadResource(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = uri
+	res, err := h.Client.ReadResource(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res.Contents, nil
+}
+
+// Close stop the client and the fake tavily api
+func (h *Harness) Close() error {
+	err := h.Client.Close()
+	h.Fake.Close()
+	return err
+}
+
+// Texts return the text contents of the result
+func Texts(res *mcp.CallToolResult) []string {
+	var texts []string
+	for _, content := range res.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return texts
+}
+
+// Images return the image contents of the result
+func Images(res *mcp.CallToolResult) []mcp.ImageContent {
+	var images []mcp.ImageContent
+	for _, content := range res.Content {
+		if image, ok := mcp.AsImageContent(content); ok {
+			images = append(images, *image)
+		}
+	}
+	return images
+}
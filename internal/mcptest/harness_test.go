@@ -0,0 +1,248 @@
+package mcptest_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/y7ut/mcp-tavily-search/internal/mcptest"
+	"github.com/y7ut/mcp-tavily-search/internal/tool"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily/tavilytest"
+)
+
+func newHarness(t *testing.T, opts ...mcptest.Option) *mcptest.Harness {
+	t.Helper()
+	h, err := mcptest.New(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func TestToolSchemas(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+
+	tests := []struct {
+		name       string
+		required   []string
+		properties []string
+	}{
+		{name: tool.SearchNewsToolName, required: []string{"keyword"}, properties: []string{"days", "limit", "search_depth", "topic", "output_format", "include_raw_content"}},
+		{name: tool.SearchNewsImageToolName, required: []string{"keyword"}, properties: []string{"days", "limit", "search_depth", "topic"}},
+		{name: tool.SearchAnswerToolName, required: []string{"question"}, properties: []string{"answer_mode", "limit", "search_depth", "topic", "days"}},
+		{name: tool.ExtractURLToolName, required: []string{"urls"}, properties: []string{"extract_depth", "include_images"}},
+		{name: tool.WebSearchToolName, required: []string{"query"}, properties: []string{"max_results", "include_answer", "include_raw_content", "include_images", "output_format"}},
+		{name: tool.SearchContentToolName, required: []string{"query"}, properties: []string{"max_tokens", "max_chars", "limit"}},
+	}
+	tools, err := h.ListTools(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != len(tests) {
+		t.Errorf("got %d tools, want %d", len(tools), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl, err := h.Tool(context.Background(), tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tl.Description == "" {
+				t.Error("tool has no description")
+			}
+			if !slices.Equal(tl.InputSchema.Required, tt.required) {
+				t.Errorf("got required %v, want %v", tl.InputSchema.Required, tt.required)
+			}
+			for _, property := range tt.properties {
+				if _, ok := tl.InputSchema.Properties[property]; !ok {
+					t.Errorf("property %s is missing", property)
+				}
+			}
+		})
+	}
+}
+
+func TestToolOutput(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+
+	tests := []struct {
+		name     string
+		tool     string
+		args     map[string]any
+		texts    int
+		images   int
+		contains string
+		json     bool
+	}{
+		{name: "news text", tool: tool.SearchNewsToolName, args: map[string]any{"keyword": "golang", "limit": 3}, texts: 3, contains: "《golang result 1》"},
+		{name: "news markdown", tool: tool.SearchNewsToolName, args: map[string]any{"keyword": "golang", "limit": 2, "output_format": "markdown"}, texts: 2, contains: "### [golang result 1]"},
+		{name: "news json", tool: tool.SearchNewsToolName, args: map[string]any{"keyword": "golang", "limit": 2, "output_format": "json"}, texts: 1, contains: `"query":"golang"`, json: true},
+		{name: "news image", tool: tool.SearchNewsImageToolName, args: map[string]any{"keyword": "golang"}, texts: tavilytest.FixtureImages, images: tavilytest.FixtureImages, contains: "Image 1 of golang."},
+		{name: "answer", tool: tool.SearchAnswerToolName, args: map[string]any{"question": "what is go"}, texts: 1, contains: "fixture answer"},
+		{name: "extract", tool: tool.ExtractURLToolName, args: map[string]any{"urls": []string{"https://go.dev", "ftp://go.dev"}}, texts: 2, contains: "failed to extract ftp://go.dev"},
+		{name: "web text", tool: tool.WebSearchToolName, args: map[string]any{"query": "golang", "include_answer": "basic"}, texts: 6, contains: "Answer: golang is a fixture answer"},
+		{name: "web json", tool: tool.WebSearchToolName, args: map[string]any{"query": "golang", "output_format": "json"}, texts: 1, contains: `"results":[`, json: true},
+		{name: "content", tool: tool.SearchContentToolName, args: map[string]any{"query": "golang"}, texts: 3, contains: "[Source 1] 《golang result 1》"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := h.CallTool(context.Background(), tt.tool, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			texts := mcptest.Texts(res)
+			if res.IsError {
+				t.Fatalf("got error result: %v", texts)
+			}
+			if len(texts) != tt.texts {
+				t.Errorf("got %d texts, want %d: %v", len(texts), tt.texts, texts)
+			}
+			if images := mcptest.Images(res); len(images) != tt.images {
+				t.Errorf("got %d images, want %d", len(images), tt.images)
+			}
+			if !strings.Contains(strings.Join(texts, "\n"), tt.contains) {
+				t.Errorf("got %v, want it to contain %q", texts, tt.contains)
+			}
+			if tt.json {
+				if !json.Valid([]byte(texts[0])) {
+					t.Errorf("got invalid json %s", texts[0])
+				}
+				if res.StructuredContent == nil {
+					t.Error("json output has no structured content")
+				}
+			}
+		})
+	}
+}
+
+func TestToolErrors(t *testing.T) {
+	t.Parallel()
+
+	retries := tavily.DefaultMaxRetries + 1
+	repeat := func(fault tavilytest.Fault, n int) []tavilytest.Fault {
+		faults := make([]tavilytest.Fault, n)
+		for i := range faults {
+			faults[i] = fault
+		}
+		return faults
+	}
+	tests := []struct {
+		name     string
+		tool     string
+		args     map[string]any
+		faults   []tavilytest.Fault
+		isError  bool
+		contains string
+	}{
+		{
+			name:     "401",
+			tool:     tool.SearchNewsToolName,
+			args:     map[string]any{"keyword": "golang"},
+			faults:   []tavilytest.Fault{{Status: http.StatusUnauthorized}},
+			isError:  true,
+			contains: "rejected the api key",
+		},
+		{
+			name:     "429 retried",
+			tool:     tool.WebSearchToolName,
+			args:     map[string]any{"query": "golang"},
+			faults:   []tavilytest.Fault{{Status: http.StatusTooManyRequests}},
+			contains: "golang result 1",
+		},
+		{
+			name: "429 with retry after",
+			tool: tool.SearchAnswerToolName,
+			args: map[string]any{"question": "what is go"},
+			// the retry after is longer than the max retry delay, it is not retried
+			faults:   []tavilytest.Fault{{Status: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}}},
+			isError:  true,
+			contains: "Retry after 30 seconds",
+		},
+		{
+			name:     "5xx retried",
+			tool:     tool.SearchNewsToolName,
+			args:     map[string]any{"keyword": "golang"},
+			faults:   []tavilytest.Fault{{Status: http.StatusBadGateway}},
+			contains: "golang result 1",
+		},
+		{
+			name:     "5xx",
+			tool:     tool.ExtractURLToolName,
+			args:     map[string]any{"urls": []string{"https://go.dev"}},
+			faults:   repeat(tavilytest.Fault{Status: http.StatusServiceUnavailable}, retries),
+			isError:  true,
+			contains: "unavailable (status 503)",
+		},
+		{
+			name:     "invalid argument",
+			tool:     tool.SearchNewsToolName,
+			args:     map[string]any{"keyword": "golang", "output_format": "yaml"},
+			isError:  true,
+			contains: "output_format error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			// each case has its own fake, the faults are consumed by the requests in order
+			h := newHarness(t)
+			h.Fake.FailNext(tt.faults...)
+			res, err := h.CallTool(context.Background(), tt.tool, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			texts := strings.Join(mcptest.Texts(res), "\n")
+			if res.IsError != tt.isError {
+				t.Fatalf("got error %v, want %v: %s", res.IsError, tt.isError, texts)
+			}
+			if !strings.Contains(texts, tt.contains) {
+				t.Errorf("got %q, want it to contain %q", texts, tt.contains)
+			}
+		})
+	}
+}
+
+func TestExtractExcludedDomain(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t, mcptest.WithDomains(nil, []string{"example.com"}))
+
+	res, err := h.CallTool(context.Background(), tool.ExtractURLToolName, map[string]any{"urls": []string{"https://go.dev", "https://docs.example.com/page"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := strings.Join(mcptest.Texts(res), "\n")
+	if !res.IsError || !strings.Contains(texts, "not allowed by the domain policy") {
+		t.Errorf("got %q, want the excluded domain rejected", texts)
+	}
+	if n := len(h.Fake.Requests()); n != 0 {
+		t.Errorf("got %d requests, want none", n)
+	}
+}
+
+func TestKeyUsageResource(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t)
+
+	if _, err := h.CallTool(context.Background(), tool.SearchNewsToolName, map[string]any{"keyword": "golang"}); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := h.ReadResource(context.Background(), tool.KeyUsageResourceURI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contents) != 1 {
+		t.Fatalf("got %d contents, want 1", len(contents))
+	}
+	text, ok := contents[0].(mcp.TextResourceContents)
+	if !ok || !strings.Contains(text.Text, `"requests": 1`) {
+		t.Errorf("got %#v, want the usage of one request", contents[0])
+	}
+}
@@ -0,0 +1,197 @@
+// Package metrics collects the prometheus metrics of the tavily traffic, the tool calls and the image downloads,
+// it implements tavily.Metrics and tool.Metrics.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+const (
+	// StatusNetworkError is the status label of the tavily requests failed without a response
+	StatusNetworkError = "network_error"
+
+	statusOK    = "ok"
+	statusError = "error"
+)
+
+var (
+	latencyBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+	resultBuckets  = []float64{0, 1, 2, 5, 10, 20, 50, 100}
+	bytesBuckets   = prometheus.ExponentialBuckets(16<<10, 4, 7)
+)
+
+// Metrics is the prometheus collectors, create it by New
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	calls           *prometheus.CounterVec
+	callResults     *prometheus.HistogramVec
+	credits         *prometheus.CounterVec
+
+	toolCalls        *prometheus.CounterVec
+	toolCallDuration *prometheus.HistogramVec
+	toolResults      *prometheus.HistogramVec
+
+	imageDownloads        *prometheus.CounterVec
+	imageDownloadDuration prometheus.Histogram
+	imageDownloadBytes    prometheus.Histogram
+}
+
+// New create the collectors registered to their own registry, with the go and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tavily_requests_total",
+			Help: "Http requests sent to tavily by endpoint and response status, retries included.",
+		}, []string{"endpoint", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "tavily_request_duration_seconds",
+			Help:    "Latency of the http requests sent to tavily.",
+			Buckets: latencyBuckets,
+		}, []string{"endpoint"}),
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tavily_calls_total",
+			Help: "Search, extract, crawl and map calls by topic, depth, status and whether served from cache.",
+		}, []string{"endpoint", "topic", "depth", "status", "cache"}),
+		callResults: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "tavily_call_results",
+			Help:    "Results returned by the successful tavily calls.",
+			Buckets: resultBuckets,
+		}, []string{"endpoint"}),
+		credits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tavily_credits_estimated_total",
+			Help: "Estimated tavily credits spent, cache hits cost nothing.",
+		}, []string{"endpoint", "depth"}),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mcp_tool_calls_total",
+			Help: "Mcp tool calls by tool, topic, depth and status.",
+		}, []string{"tool", "topic", "depth", "status"}),
+		toolCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mcp_tool_call_duration_seconds",
+			Help:    "Latency of the mcp tool calls.",
+			Buckets: latencyBuckets,
+		}, []string{"tool"}),
+		toolResults: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mcp_tool_call_results",
+			Help:    "Results returned by the mcp tool calls.",
+			Buckets: resultBuckets,
+		}, []string{"tool"}),
+		imageDownloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mcp_image_downloads_total",
+			Help: "Image downloads by status.",
+		}, []string{"status"}),
+		imageDownloadDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "mcp_image_download_duration_seconds",
+			Help:    "Latency of the image downloads.",
+			Buckets: latencyBuckets,
+		}),
+		imageDownloadBytes: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "mcp_image_download_bytes",
+			Help:    "Size of the downloaded images, before they are re-encoded.",
+			Buckets: bytesBuckets,
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.requestDuration, m.calls, m.callResults, m.credits,
+		m.toolCalls, m.toolCallDuration, m.toolResults,
+		m.imageDownloads, m.imageDownloadDuration, m.imageDownloadBytes,
+	)
+	return m
+}
+
+// ObserveRequest implement tavily.Metrics
+func (m *Metrics) ObserveRequest(endpoint string, status int, latency time.Duration) {
+	label := StatusNetworkError
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	m.requests.WithLabelValues(endpoint, label).Inc()
+	m.requestDuration.WithLabelValues(endpoint).Observe(latency.Seconds())
+}
+
+// ObserveCall implement tavily.Metrics
+func (m *Metrics) ObserveCall(stats tavily.CallStats) {
+	status, cache := statusOK, "miss"
+	if stats.Err != nil {
+		status = statusError
+	}
+	if stats.CacheHit {
+		cache = "hit"
+	}
+	m.calls.WithLabelValues(stats.Endpoint, stats.Topic, stats.Depth, status, cache).Inc()
+	if stats.Err != nil {
+		return
+	}
+	m.callResults.WithLabelValues(stats.Endpoint).Observe(float64(stats.Results))
+	if stats.Credits > 0 {
+		m.credits.WithLabelValues(stats.Endpoint, stats.Depth).Add(float64(stats.Credits))
+	}
+}
+
+// ObserveToolCall implement tool.Metrics
+func (m *Metrics) ObserveToolCall(tool, topic, depth, status string, latency time.Duration, results int) {
+	m.toolCalls.WithLabelValues(tool, topic, depth, status).Inc()
+	m.toolCallDuration.WithLabelValues(tool).Observe(latency.Seconds())
+	m.toolResults.WithLabelValues(tool).Observe(float64(results))
+}
+
+// ObserveImageDownload implement tool.Metrics
+func (m *Metrics) ObserveImageDownload(status string, latency time.Duration, bytes int) {
+	m.imageDownloads.WithLabelValues(status).Inc()
+	m.imageDownloadDuration.Observe(latency.Seconds())
+	if bytes > 0 {
+		m.imageDownloadBytes.Observe(float64(bytes))
+	}
+}
+
+// Handler return the http handler serving the metrics in the prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Serve serve the metrics on addr under /metrics until ctx is done
+func (m *Metrics) Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/y7ut/mcp-tavily-search/pkg/param"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+// TavilySearchAnswerHandler is the handler for the search answer tool, return the answer first, then the numbered sources
+func TavilySearchAnswerHandler(client TavilyClient, tools *Tools) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var question string
+		if err := param.Assign(&question, request.GetArguments()["question"]); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("question error: %v", err)), nil
+		}
+
+		mode := string(tavily.AnswerBasic)
+		if v, ok := request.GetArguments()["answer_mode"]; ok {
+			if err := param.Assign(&mode, v); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("answer_mode error: %v", err)), nil
+			}
+		}
+		if mode != string(tavily.AnswerBasic) && mode != string(tavily.AnswerAdvanced) {
+			return mcp.NewToolResultError(fmt.Sprintf("answer_mode error: %s is not a valid answer mode", mode)), nil
+		}
+
+		result, err := client.SearchAnswer(
+			ctx,
+			question,
+			tavily.AnswerMode(mode),
+			tavily.WithOption("topic", tools.argument(request, "topic")),
+			tavily.WithOption("days", tools.argument(request, "days")),
+			tavily.WithOption("limit", tools.argument(request, "limit")),
+			tavily.WithOption("search_depth", tools.argument(request, "search_depth")),
+			tavily.WithOption("include_domains", request.GetArguments()["include_domains"]),
+			tavily.WithOption("exclude_domains", request.GetArguments()["exclude_domains"]),
+		)
+
+		if err != nil {
+			return toolError(err), nil
+		}
+
+		if result.Answer == nil || strings.TrimSpace(*result.Answer) == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("no answer generated for question: %s", question)), nil
+		}
+
+		recordResults(ctx, len(result.Results))
+		var text strings.Builder
+		text.WriteString(strings.TrimSpace(*result.Answer))
+		if len(result.Results) > 0 {
+			text.WriteString("\n\nSources:")
+			for i, source := range result.Results {
+				fmt.Fprintf(&text, "\n%d. 《%s》: %s", i+1, source.Title, source.URL)
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Result: searchResult(result),
+			Content: append([]mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: text.String(),
+				},
+			}, downgradeNote(result)...),
+		}, nil
+	}
+}
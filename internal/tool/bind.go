@@ -1,69 +1,281 @@
 package tool
 
 import (
+	"fmt"
+
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
-	"github.com/y7ut/mcp-tavily-search/internal/tavily"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
 )
 
 const (
 	ImageSearchReferencesLimit = 1
 	NewsSearchReferencesLimit  = 5
+	AnswerSourcesLimit         = 5
+	ContentSourcesLimit        = 3
+	WebSearchResultsLimit      = 5
+
+	KeyUsageResourceURI = "tavily://keys/usage"
 )
 
-// Bind binds the search tool
-func Bind(server *server.MCPServer) {
+// Bind binds the search tools calling the tavily client, the tools read their settings from tools
+func Bind(server *server.MCPServer, client TavilyClient, tools *Tools) {
+	newsDefaults := tools.Defaults(SearchNewsToolName)
+	imageDefaults := tools.Defaults(SearchNewsImageToolName)
+	answerDefaults := tools.Defaults(SearchAnswerToolName)
+	contentDefaults := tools.Defaults(SearchContentToolName)
+	webDefaults := tools.Defaults(WebSearchToolName)
+
 	// Add tool
-	searchTool := mcp.NewTool("search_news",
-		mcp.WithDescription("Get recent news from tavily by keyword"),
+	searchTool := mcp.NewTool(SearchNewsToolName,
+		mcp.WithDescription("Get recent news articles about an event, company, person or topic by keyword, with their title, url and snippet. "+
+			"Use it for current events and headlines, use web_search for general or technical questions. "+
+			"With output_format \"json\" the result is a json object, also returned as structured content: "+searchOutputSchema),
 		mcp.WithString("keyword",
 			mcp.Required(),
 			mcp.Description("Keyword to search for."),
 		),
 		mcp.WithNumber("days",
-			mcp.DefaultNumber(7),
-			mcp.Description("Number of days to search, default is 7 days, max is 30."),
+			mcp.DefaultNumber(float64(newsDefaults.Days)),
+			mcp.Description(fmt.Sprintf("Number of days to search, default is %d days, max is 30.", newsDefaults.Days)),
 		),
 		mcp.WithNumber("limit",
-			mcp.DefaultNumber(NewsSearchReferencesLimit),
-			mcp.Description("Number of news to return, default is 5, max is 10."),
+			mcp.DefaultNumber(float64(newsDefaults.Limit)),
+			mcp.Description(fmt.Sprintf("Number of news to return, default is %d, max is %d.", newsDefaults.Limit, tavily.MaxResultsLimit)),
 		),
 		mcp.WithString("search_depth",
 			mcp.Enum(tavily.DepthAdvanced, tavily.DepthBasic),
-			mcp.DefaultString(tavily.DepthBasic),
+			mcp.DefaultString(newsDefaults.SearchDepth),
 			mcp.Description("The depth of the search. It can be \"basic\" or \"advanced\". Default is \"basic\" unless specified otherwise in a given method. "),
 		),
 		mcp.WithString("topic",
 			mcp.Enum(tavily.TopicGeneral, tavily.TopicNews),
-			mcp.DefaultString(tavily.TopicNews),
-			mcp.Description("The topic of the search, default is news. topic news will retrun high quality news, topic general will return unprocessed website pages."),
+			mcp.DefaultString(newsDefaults.Topic),
+			mcp.Description("The topic of the search, default is "+newsDefaults.Topic+". topic news will retrun high quality news, topic general will return unprocessed website pages."),
+		),
+		mcp.WithArray("include_domains",
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.Description("Only search these domains, like docs.python.org. They can only narrow the domains allowed by the server."),
+		),
+		mcp.WithArray("exclude_domains",
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.Description("Never search these domains, in addition to the domains excluded by the server."),
+		),
+		mcp.WithString("output_format",
+			mcp.Enum(OutputText, OutputMarkdown, OutputJSON),
+			mcp.DefaultString(OutputText),
+			mcp.Description("The format of the results. \"text\" is the title, url and content of each news, \"markdown\" adds the published date and the score, \"json\" returns the full result objects. Default is \"text\"."),
+		),
+		mcp.WithBoolean("include_raw_content",
+			mcp.DefaultBool(false),
+			mcp.Description("Whether to include the cleaned full content of each page, only returned with output_format \"json\". Default is false."),
 		),
 	)
-	searchImageTool := mcp.NewTool("search_news_image",
-		mcp.WithDescription("Get recent news image from tavily by keyword"),
+	searchImageTool := mcp.NewTool(SearchNewsImageToolName,
+		mcp.WithDescription("Get the images of recent news by keyword, the images are downloaded and returned with their descriptions. Use it only when the images of a news event are wanted."),
 		mcp.WithString("keyword",
 			mcp.Required(),
 			mcp.Description("Keyword to search for."),
 		),
 		mcp.WithNumber("days",
-			mcp.DefaultNumber(7),
-			mcp.Description("Number of days to search, default is 7 days, max is 30."),
+			mcp.DefaultNumber(float64(imageDefaults.Days)),
+			mcp.Description(fmt.Sprintf("Number of days to search, default is %d days, max is 30.", imageDefaults.Days)),
 		),
 		mcp.WithNumber("limit",
-			mcp.DefaultNumber(ImageSearchReferencesLimit),
-			mcp.Description("Number of Image to return, default is 1, max is 2."),
+			mcp.DefaultNumber(float64(imageDefaults.Limit)),
+			mcp.Description(fmt.Sprintf("Number of Image to return, default is %d, max is 2.", imageDefaults.Limit)),
 		),
 		mcp.WithString("search_depth",
 			mcp.Enum(tavily.DepthAdvanced, tavily.DepthBasic),
-			mcp.DefaultString(tavily.DepthBasic),
+			mcp.DefaultString(imageDefaults.SearchDepth),
 			mcp.Description("The depth of the search. It can be \"basic\" or \"advanced\". Default is \"basic\" unless specified otherwise in a given method. "),
 		),
 		mcp.WithString("topic",
 			mcp.Enum(tavily.TopicGeneral, tavily.TopicNews),
-			mcp.DefaultString(tavily.TopicNews),
-			mcp.Description("The topic of the search, default is news. topic news will retrun high quality news, topic general will return unprocessed website pages."),
+			mcp.DefaultString(imageDefaults.Topic),
+			mcp.Description("The topic of the search, default is "+imageDefaults.Topic+". topic news will retrun high quality news, topic general will return unprocessed website pages."),
+		),
+		mcp.WithArray("include_domains",
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.Description("Only search these domains, like docs.python.org. They can only narrow the domains allowed by the server."),
+		),
+		mcp.WithArray("exclude_domains",
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.Description("Never search these domains, in addition to the domains excluded by the server."),
+		),
+	)
+	searchAnswerTool := mcp.NewTool(SearchAnswerToolName,
+		mcp.WithDescription("Ask a question and get the answer synthesized by tavily from web search, followed by the numbered sources it cites. Use it for factual Q&A instead of summarizing raw search results yourself."),
+		mcp.WithString("question",
+			mcp.Required(),
+			mcp.Description("The question to answer."),
+		),
+		mcp.WithString("answer_mode",
+			mcp.Enum(string(tavily.AnswerBasic), string(tavily.AnswerAdvanced)),
+			mcp.DefaultString(string(tavily.AnswerBasic)),
+			mcp.Description("The mode of the answer. \"basic\" is a quick short answer, \"advanced\" is a more detailed answer. Default is \"basic\"."),
+		),
+		mcp.WithNumber("limit",
+			mcp.DefaultNumber(float64(answerDefaults.Limit)),
+			mcp.Description(fmt.Sprintf("Number of sources to return, default is %d, max is %d.", answerDefaults.Limit, tavily.MaxResultsLimit)),
+		),
+		mcp.WithString("search_depth",
+			mcp.Enum(tavily.DepthAdvanced, tavily.DepthBasic),
+			mcp.DefaultString(answerDefaults.SearchDepth),
+			mcp.Description("The depth of the search. It can be \"basic\" or \"advanced\". Default is \"basic\"."),
+		),
+		mcp.WithString("topic",
+			mcp.Enum(tavily.TopicGeneral, tavily.TopicNews),
+			mcp.DefaultString(answerDefaults.Topic),
+			mcp.Description("The topic of the search, default is "+answerDefaults.Topic+". Use news for questions about recent events."),
+		),
+		mcp.WithNumber("days",
+			mcp.DefaultNumber(float64(answerDefaults.Days)),
+			mcp.Description(fmt.Sprintf("Number of days to search when topic is news, default is %d days, max is 30.", answerDefaults.Days)),
+		),
+		mcp.WithArray("include_domains",
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.Description("Only search these domains, like docs.python.org. They can only narrow the domains allowed by the server."),
+		),
+		mcp.WithArray("exclude_domains",
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.Description("Never search these domains, in addition to the domains excluded by the server."),
+		),
+	)
+	extractTool := mcp.NewTool(ExtractURLToolName,
+		mcp.WithDescription("Extract the full readable content of web pages from tavily by url, use it when you already know the url of the page."),
+		mcp.WithArray("urls",
+			mcp.Required(),
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.MinItems(1),
+			mcp.MaxItems(tavily.MaxExtractURLs),
+			mcp.Description("List of urls to extract content from, max is 20."),
+		),
+		mcp.WithString("extract_depth",
+			mcp.Enum(tavily.DepthAdvanced, tavily.DepthBasic),
+			mcp.DefaultString(tavily.DepthBasic),
+			mcp.Description("The depth of the extraction. \"advanced\" retrieves more data such as tables and embedded content, but costs more. Default is \"basic\"."),
+		),
+		mcp.WithBoolean("include_images",
+			mcp.DefaultBool(false),
+			mcp.Description("Whether to include the image urls found on each page, default is false."),
+		),
+	)
+	webSearchTool := mcp.NewTool(WebSearchToolName,
+		mcp.WithDescription("Search the web for any question: technical documentation, programming errors, products, people, definitions, how-tos and facts. "+
+			"Returns the title, url, snippet and relevance score of each page, optionally with an answer generated from the results, the cleaned full content of the pages and related images. "+
+			"Use it by default for general questions, use search_news for recent news. "+
+			"With output_format \"json\" the result is a json object, also returned as structured content: "+searchOutputSchema),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("The search query, a question or keywords."),
+		),
+		mcp.WithNumber("max_results",
+			mcp.DefaultNumber(float64(webDefaults.Limit)),
+			mcp.Min(1),
+			mcp.Max(tavily.MaxResultsLimit),
+			mcp.Description(fmt.Sprintf("Number of results to return, default is %d, max is %d.", webDefaults.Limit, tavily.MaxResultsLimit)),
+		),
+		mcp.WithString("topic",
+			mcp.Enum(tavily.TopicGeneral, tavily.TopicNews),
+			mcp.DefaultString(webDefaults.Topic),
+			mcp.Description("The topic of the search, default is "+webDefaults.Topic+". Use news only for recent events."),
+		),
+		mcp.WithString("search_depth",
+			mcp.Enum(tavily.DepthAdvanced, tavily.DepthBasic),
+			mcp.DefaultString(webDefaults.SearchDepth),
+			mcp.Description("The depth of the search. \"advanced\" returns more relevant snippets but costs twice the credits. Default is \"basic\"."),
+		),
+		mcp.WithNumber("days",
+			mcp.DefaultNumber(float64(webDefaults.Days)),
+			mcp.Description(fmt.Sprintf("Number of days to search when topic is news, default is %d days, max is 30.", webDefaults.Days)),
+		),
+		mcp.WithString("include_answer",
+			mcp.Enum("false", string(tavily.AnswerBasic), string(tavily.AnswerAdvanced)),
+			mcp.DefaultString("false"),
+			mcp.Description("Whether to include an answer generated by tavily from the results. \"basic\" is a quick short answer, \"advanced\" is more detailed. Default is \"false\"."),
+		),
+		mcp.WithBoolean("include_raw_content",
+			mcp.DefaultBool(false),
+			mcp.Description("Whether to include the cleaned full content of each page, it can be long. Default is false."),
+		),
+		mcp.WithBoolean("include_images",
+			mcp.DefaultBool(false),
+			mcp.Description("Whether to include the urls of the images related to the query. Default is false."),
+		),
+		mcp.WithBoolean("include_image_descriptions",
+			mcp.DefaultBool(false),
+			mcp.Description("Whether to include a description of each image, only with include_images. Default is false."),
+		),
+		mcp.WithArray("include_domains",
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.Description("Only search these domains, like docs.python.org. They can only narrow the domains allowed by the server."),
+		),
+		mcp.WithArray("exclude_domains",
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.Description("Never search these domains, in addition to the domains excluded by the server."),
+		),
+		mcp.WithString("output_format",
+			mcp.Enum(OutputText, OutputMarkdown, OutputJSON),
+			mcp.DefaultString(OutputText),
+			mcp.Description("The format of the results. \"text\" is the title, url and content of each page, \"markdown\" adds the published date and the score, \"json\" returns the full result objects. Default is \"text\"."),
+		),
+	)
+	searchContentTool := mcp.NewTool(SearchContentToolName,
+		mcp.WithDescription("Search the web and read the pages found. The boilerplate of each page is stripped, the pages are split into chunks, "+
+			"and the chunks most relevant to the query are returned within the token or character budget, each page delimited as a numbered source. "+
+			"Use it when the snippets of search_news are not enough to answer."),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("The query to search for, the chunks are ranked by it."),
+		),
+		mcp.WithNumber("max_tokens",
+			mcp.DefaultNumber(DefaultContentTokens),
+			mcp.Min(100),
+			mcp.Max(MaxContentTokens),
+			mcp.Description(fmt.Sprintf("The budget of the output in tokens, estimated as 4 characters each. Default is %d, max is %d.", DefaultContentTokens, MaxContentTokens)),
+		),
+		mcp.WithNumber("max_chars",
+			mcp.Min(400),
+			mcp.Description("The budget of the output in characters, the smaller budget is used when max_tokens is also set. The least relevant pages are left out when the budget is too small for all of them."),
+		),
+		mcp.WithNumber("limit",
+			mcp.DefaultNumber(float64(contentDefaults.Limit)),
+			mcp.Description(fmt.Sprintf("Number of pages to read, default is %d, max is %d.", contentDefaults.Limit, tavily.MaxResultsLimit)),
+		),
+		mcp.WithString("search_depth",
+			mcp.Enum(tavily.DepthAdvanced, tavily.DepthBasic),
+			mcp.DefaultString(contentDefaults.SearchDepth),
+			mcp.Description("The depth of the search. It can be \"basic\" or \"advanced\". Default is \"basic\"."),
+		),
+		mcp.WithString("topic",
+			mcp.Enum(tavily.TopicGeneral, tavily.TopicNews),
+			mcp.DefaultString(contentDefaults.Topic),
+			mcp.Description("The topic of the search, default is "+contentDefaults.Topic+". Use news for recent events."),
+		),
+		mcp.WithNumber("days",
+			mcp.DefaultNumber(float64(contentDefaults.Days)),
+			mcp.Description(fmt.Sprintf("Number of days to search when topic is news, default is %d days, max is 30.", contentDefaults.Days)),
 		),
+		mcp.WithArray("include_domains",
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.Description("Only search these domains, like docs.python.org. They can only narrow the domains allowed by the server."),
+		),
+		mcp.WithArray("exclude_domains",
+			mcp.Items(map[string]any{"type": "string"}),
+			mcp.Description("Never search these domains, in addition to the domains excluded by the server."),
+		),
+	)
+	server.AddTool(webSearchTool, tools.withDefaults(TavilyWebSearchHandler(client, tools)))
+	server.AddTool(searchTool, tools.withDefaults(TavilySearchHandler(client, tools)))
+	server.AddTool(searchImageTool, tools.withDefaults(TavilySearchImageHandler(client, tools)))
+	server.AddTool(searchAnswerTool, tools.withDefaults(TavilySearchAnswerHandler(client, tools)))
+	server.AddTool(extractTool, tools.withDefaults(TavilyExtractHandler(client)))
+	server.AddTool(searchContentTool, tools.withDefaults(TavilySearchWithContentHandler(client, tools)))
+
+	keyUsageResource := mcp.NewResource(KeyUsageResourceURI, "Tavily api key usage",
+		mcp.WithResourceDescription("Usage counters of each tavily api key in the pool, the keys are masked."),
+		mcp.WithMIMEType("application/json"),
 	)
-	server.AddTool(searchTool, TavilySearchHandler)
-	server.AddTool(searchImageTool, TavilySearchImageHandler)
+	server.AddResource(keyUsageResource, KeyUsageHandler(client))
 }
@@ -0,0 +1,354 @@
+package tool
+
+import (
+	"cmp"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultChunkChars is the size of the chunks the pages are split into
+	DefaultChunkChars = 800
+
+	// charsPerToken is the estimate of the characters of a token, close enough for english text
+	charsPerToken = 4
+
+	// maxMenuWords is the most words of a line repeated across the pages to be taken as a menu or a footer
+	maxMenuWords = 6
+
+	// chunkSeparator join the paragraphs of a chunk and the adjacent chunks of a page, gapSeparator mark the chunks left out between two picked ones
+	chunkSeparator = "\n\n"
+	gapSeparator   = "\n\n[...]\n\n"
+)
+
+var (
+	markdownImage = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	markdownLink  = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	bareURL       = regexp.MustCompile(`https?://\S+`)
+	spaces        = regexp.MustCompile(`[ \t\p{Zs}]+`)
+	sentenceEnd   = regexp.MustCompile(`[.!?。！？]\s`)
+)
+
+// boilerplate is the lowercase phrases of the lines which are navigation, cookie banners and footers, not content
+var boilerplate = []string{
+	"accept all cookies", "accept cookies", "cookie policy", "cookie settings", "we use cookies",
+	"privacy policy", "terms of service", "terms of use", "all rights reserved",
+	"skip to content", "skip to main content", "sign in", "log in", "sign up", "subscribe to",
+	"share this article", "share on", "follow us", "back to top", "advertisement", "read more",
+}
+
+// stopwords is the words too common to rank the chunks by
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "how": true, "in": true, "is": true, "it": true, "of": true, "on": true,
+	"or": true, "that": true, "the": true, "this": true, "to": true, "was": true, "what": true,
+	"when": true, "where": true, "which": true, "who": true, "why": true, "with": true,
+}
+
+// stripBoilerplate drop the markup, the navigation, the banners and the repeated lines of the page,
+// and return its paragraphs, repeated is the lines found on the other pages of the search, see repeatedLines
+func stripBoilerplate(page string, repeated map[string]bool) []string {
+	seen := map[string]bool{}
+	var paragraphs []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, " "))
+			current = nil
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(page, "\r\n", "\n"), "\n") {
+		line, heading := cleanLine(line)
+		if line == "" {
+			flush()
+			continue
+		}
+		key := strings.ToLower(line)
+		if seen[key] || isBoilerplate(key, heading, repeated) {
+			continue
+		}
+		seen[key] = true
+		current = append(current, line)
+	}
+	flush()
+	return paragraphs
+}
+
+// cleanLine drop the markup of the line, heading is true if it is a markdown heading
+func cleanLine(line string) (text string, heading bool) {
+	line = markdownImage.ReplaceAllString(line, "")
+	line = markdownLink.ReplaceAllString(line, "$1")
+	line = bareURL.ReplaceAllString(line, "")
+	line = strings.TrimSpace(spaces.ReplaceAllString(line, " "))
+	heading = strings.HasPrefix(line, "#")
+	return strings.TrimSpace(strings.TrimLeft(line, "#>*-|+ ")), heading
+}
+
+// repeatedLines return the lowercase short lines found on more than one of the pages, like the menus and the footers
+// of a site, headings are not counted
+func repeatedLines(pages []string) map[string]bool {
+	count := map[string]int{}
+	for _, page := range pages {
+		seen := map[string]bool{}
+		for _, line := range strings.Split(strings.ReplaceAll(page, "\r\n", "\n"), "\n") {
+			line, heading := cleanLine(line)
+			key := strings.ToLower(line)
+			if key == "" || heading || seen[key] || len(strings.Fields(key)) > maxMenuWords {
+				continue
+			}
+			seen[key] = true
+			count[key]++
+		}
+	}
+	repeated := map[string]bool{}
+	for key, n := range count {
+		if n > 1 {
+			repeated[key] = true
+		}
+	}
+	return repeated
+}
+
+// isBoilerplate return true if the lowercase line is a short line repeated across the pages or of a banner,
+// or has neither letters nor digits, headings are never taken as menus
+func isBoilerplate(line string, heading bool, repeated map[string]bool) bool {
+	if !strings.ContainsFunc(line, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) {
+		return true
+	}
+	// long lines are content even if they mention cookies
+	if len(strings.Fields(line)) > 12 {
+		return false
+	}
+	for _, phrase := range boilerplate {
+		if strings.Contains(line, phrase) {
+			return true
+		}
+	}
+	return !heading && repeated[line]
+}
+
+// chunkParagraphs merge the paragraphs into chunks of about size characters,
+// paragraphs longer than size are split at the sentences
+func chunkParagraphs(paragraphs []string, size int) []string {
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+	}
+	for _, paragraph := range paragraphs {
+		for _, piece := range splitLong(paragraph, size) {
+			if current.Len() > 0 && utf8.RuneCountInString(current.String())+len(chunkSeparator)+utf8.RuneCountInString(piece) > size {
+				flush()
+			}
+			if current.Len() > 0 {
+				current.WriteString(chunkSeparator)
+			}
+			current.WriteString(piece)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitLong split the text longer than size at the sentences, sentences longer than size are cut at size
+func splitLong(text string, size int) []string {
+	if utf8.RuneCountInString(text) <= size {
+		return []string{text}
+	}
+	var pieces []string
+	var current strings.Builder
+	start := 0
+	var sentences []string
+	for _, loc := range sentenceEnd.FindAllStringIndex(text, -1) {
+		sentences = append(sentences, text[start:loc[1]])
+		start = loc[1]
+	}
+	sentences = append(sentences, text[start:])
+	for _, sentence := range sentences {
+		for utf8.RuneCountInString(sentence) > size {
+			if current.Len() > 0 {
+				pieces = append(pieces, strings.TrimSpace(current.String()))
+				current.Reset()
+			}
+			cut := truncateRunes(sentence, size)
+			pieces = append(pieces, strings.TrimSpace(cut))
+			sentence = sentence[len(cut):]
+		}
+		if current.Len() > 0 && utf8.RuneCountInString(current.String())+utf8.RuneCountInString(sentence) > size {
+			pieces = append(pieces, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+		current.WriteString(sentence)
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		pieces = append(pieces, strings.TrimSpace(current.String()))
+	}
+	return pieces
+}
+
+// truncateRunes return the first n runes of s
+func truncateRunes(s string, n int) string {
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos]
+		}
+		i++
+	}
+	return s
+}
+
+// terms return the lowercase words of the text, without the stopwords
+func terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	res := words[:0]
+	for _, word := range words {
+		if !stopwords[word] && (utf8.RuneCountInString(word) > 1 || !isASCII(word)) {
+			res = append(res, word)
+		}
+	}
+	return res
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// chunk is a chunk of a page and its relevance to the query
+type chunk struct {
+	source int
+	index  int
+	text   string
+	score  float64
+}
+
+// rankChunks score each chunk by the bm25 of the query terms, over all the chunks of all the pages
+func rankChunks(query string, chunks []chunk) {
+	queryTerms := slices.Compact(slices.Sorted(slices.Values(terms(query))))
+	if len(queryTerms) == 0 || len(chunks) == 0 {
+		return
+	}
+
+	const k1, b = 1.2, 0.75
+	freqs := make([]map[string]int, len(chunks))
+	lengths := make([]int, len(chunks))
+	docFreq := map[string]int{}
+	total := 0
+	for i, c := range chunks {
+		freqs[i] = map[string]int{}
+		for _, term := range terms(c.text) {
+			freqs[i][term]++
+			lengths[i]++
+		}
+		for term := range freqs[i] {
+			docFreq[term]++
+		}
+		total += lengths[i]
+	}
+	avgLength := math.Max(float64(total)/float64(len(chunks)), 1)
+
+	for i := range chunks {
+		score := 0.0
+		for _, term := range queryTerms {
+			tf := float64(freqs[i][term])
+			if tf == 0 {
+				continue
+			}
+			idf := math.Log(1 + (float64(len(chunks))-float64(docFreq[term])+0.5)/(float64(docFreq[term])+0.5))
+			score += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(lengths[i])/avgLength))
+		}
+		chunks[i].score = score
+	}
+}
+
+// selectChunks pick the chunks within the budget of characters, first the best chunk of each source in the order of
+// the sources, then the best of the rest. each chunk after the first of its source is charged the longest separator,
+// so the joined chunks never exceed the budget. the picked chunks are returned in the order of the sources and the pages
+func selectChunks(chunks []chunk, budget int) (picked []chunk) {
+	used := 0
+	taken := make([]bool, len(chunks))
+	started := map[int]bool{}
+	cost := func(i int) int {
+		n := utf8.RuneCountInString(chunks[i].text)
+		if started[chunks[i].source] {
+			n += utf8.RuneCountInString(gapSeparator)
+		}
+		return n
+	}
+	take := func(i int) {
+		used += cost(i)
+		taken[i] = true
+		started[chunks[i].source] = true
+		picked = append(picked, chunks[i])
+	}
+	fits := func(i int) bool {
+		return used+cost(i) <= budget
+	}
+
+	order := make([]int, len(chunks))
+	for i := range order {
+		order[i] = i
+	}
+	// stable, so the chunks of the same score keep the order of the sources and the pages
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(chunks[b].score, chunks[a].score)
+	})
+
+	best := map[int]int{}
+	var sources []int
+	for _, i := range order {
+		if _, ok := best[chunks[i].source]; !ok {
+			best[chunks[i].source] = i
+			sources = append(sources, chunks[i].source)
+		}
+	}
+	slices.Sort(sources)
+	for _, source := range sources {
+		if i := best[source]; fits(i) {
+			take(i)
+		}
+	}
+	for _, i := range order {
+		if !taken[i] && fits(i) {
+			take(i)
+		}
+	}
+
+	slices.SortFunc(picked, func(a, b chunk) int {
+		if a.source != b.source {
+			return a.source - b.source
+		}
+		return a.index - b.index
+	})
+	return picked
+}
+
+// joinChunks join the picked chunks of a source, the chunks left out between them are marked
+func joinChunks(picked []chunk) string {
+	var text strings.Builder
+	for i, c := range picked {
+		if i > 0 {
+			if c.index == picked[i-1].index+1 {
+				text.WriteString(chunkSeparator)
+			} else {
+				text.WriteString(gapSeparator)
+			}
+		}
+		text.WriteString(c.text)
+	}
+	return text.String()
+}
@@ -0,0 +1,149 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+// page return a page of n paragraphs about the topic
+func page(topic string, n int) string {
+	paragraphs := make([]string, n)
+	for i := range paragraphs {
+		paragraphs[i] = fmt.Sprintf("Paragraph %d explains how %s works in practice. It covers the details, the trade-offs and the common mistakes of %s in production systems.", i+1, topic, topic)
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func TestStripBoilerplate(t *testing.T) {
+	docs := strings.Join([]string{
+		"Home", "Docs Blog Community", "",
+		"# Overview", "", "Go is an open source programming language.", "",
+		"Installation", "", "Download the archive and extract it into /usr/local.", "",
+		"| Go 1.22 |", "| 42 |", "", "---", "",
+		"We use cookies to improve your experience", "", "© 2026 The Go Authors",
+	}, "\n")
+	blog := strings.Join([]string{
+		"Home", "Docs Blog Community", "",
+		"# Overview", "", "Go 1.22 changes the loop variables.", "",
+		"© 2026 The Go Authors",
+	}, "\n")
+	repeated := repeatedLines([]string{docs, blog})
+
+	got := stripBoilerplate(docs, repeated)
+	want := []string{
+		"Overview", "Go is an open source programming language.",
+		"Installation", "Download the archive and extract it into /usr/local.",
+		"Go 1.22 | 42 |",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got paragraphs %q, want %q", got, want)
+	}
+	// without the other pages, the short lines are content
+	if got := stripBoilerplate(blog, nil); !slices.Contains(got, "Home Docs Blog Community") {
+		t.Errorf("got paragraphs %q, want the short line of a single page kept", got)
+	}
+}
+
+func TestSelectChunks(t *testing.T) {
+	var chunks []chunk
+	for source, topic := range []string{"golang channels", "rust ownership", "golang generics"} {
+		for j, c := range chunkParagraphs(stripBoilerplate(page(topic, 12), nil), 300) {
+			chunks = append(chunks, chunk{source: source, index: j, text: c})
+		}
+	}
+	rankChunks("golang", chunks)
+
+	for _, budget := range []int{250, 600, 1000, 2500, 100000} {
+		t.Run(fmt.Sprint(budget), func(t *testing.T) {
+			picked := selectChunks(chunks, budget)
+			total := 0
+			sources := map[int][]chunk{}
+			for _, c := range picked {
+				sources[c.source] = append(sources[c.source], c)
+			}
+			for _, own := range sources {
+				total += utf8.RuneCountInString(joinChunks(own))
+			}
+			if total > budget {
+				t.Errorf("got %d characters, want at most %d", total, budget)
+			}
+			if budget >= 1000 && len(sources) != 3 {
+				t.Errorf("got chunks of %d sources, want a chunk of each source", len(sources))
+			}
+			if budget == 100000 && len(picked) != len(chunks) {
+				t.Errorf("got %d chunks, want all %d", len(picked), len(chunks))
+			}
+		})
+	}
+}
+
+func TestJoinChunks(t *testing.T) {
+	got := joinChunks([]chunk{{index: 0, text: "a"}, {index: 1, text: "b"}, {index: 3, text: "c"}})
+	if want := "a\n\nb\n\n[...]\n\nc"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSearchWithContentBudget(t *testing.T) {
+	resp := &tavily.TavilySearchResponse{Query: "golang"}
+	for i, topic := range []string{"golang channels", "golang generics", "golang modules"} {
+		raw := page(topic, 20)
+		resp.Results = append(resp.Results, tavily.TavilySearchResult{
+			Title:      strings.ToUpper(topic[:1]) + topic[1:],
+			URL:        fmt.Sprintf("https://example.com/%d", i),
+			Content:    "Snippet about " + topic,
+			RawContent: &raw,
+		})
+	}
+	tools := newTools(t, nil)
+
+	tests := []struct {
+		name    string
+		args    map[string]any
+		budget  int
+		sources int
+	}{
+		{name: "smallest max_chars", args: map[string]any{"max_chars": 400}, budget: 400, sources: 1},
+		{name: "max_chars", args: map[string]any{"max_chars": 1200}, budget: 1200, sources: 3},
+		{name: "smallest max_tokens", args: map[string]any{"max_tokens": 100}, budget: 400, sources: 1},
+		{name: "max_tokens", args: map[string]any{"max_tokens": 1000}, budget: 4000, sources: 3},
+		{name: "smaller of both", args: map[string]any{"max_tokens": 1000, "max_chars": 800}, budget: 800, sources: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockClient{resp: resp}
+			tt.args["query"] = "golang generics"
+			res, err := TavilySearchWithContentHandler(client, tools)(context.Background(), callRequest(SearchContentToolName, tt.args))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.IsError {
+				t.Fatalf("got error %s", callText(res))
+			}
+			total, sources := 0, 0
+			for _, content := range res.Content {
+				if text, ok := mcp.AsTextContent(content); ok && strings.HasPrefix(text.Text, "[Source ") {
+					text := text.Text
+					sources++
+					total += utf8.RuneCountInString(text)
+				}
+			}
+			if total > tt.budget {
+				t.Errorf("got %d characters, want at most %d", total, tt.budget)
+			}
+			if sources != tt.sources {
+				t.Errorf("got %d sources, want %d", sources, tt.sources)
+			}
+			if note := fmt.Sprintf("of %d of 3 pages are kept", tt.sources); tt.sources < 3 && !strings.Contains(callText(res), note) {
+				t.Errorf("got %q, want the note of the pages left out", callText(res))
+			}
+		})
+	}
+}
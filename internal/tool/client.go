@@ -0,0 +1,32 @@
+package tool
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type clientAddrKey struct{}
+
+// WithClientAddr return a copy of ctx carrying the network address of the connected client
+func WithClientAddr(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, clientAddrKey{}, addr)
+}
+
+// ClientAddrFromContext return the network address of the client which issued the tool call, empty over stdio
+func ClientAddrFromContext(ctx context.Context) string {
+	addr, _ := ctx.Value(clientAddrKey{}).(string)
+	return addr
+}
+
+// ClientFromContext return the identity of the client which issued the tool call, the mcp session id,
+// or the address of the client when the transport has no session, and "stdio" when neither is found
+func ClientFromContext(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil && session.SessionID() != "" {
+		return session.SessionID()
+	}
+	if addr := ClientAddrFromContext(ctx); addr != "" {
+		return addr
+	}
+	return "stdio"
+}
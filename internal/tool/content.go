@@ -0,0 +1,172 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/y7ut/mcp-tavily-search/pkg/param"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+const (
+	// DefaultContentTokens is the default token budget of the search with content tool
+	DefaultContentTokens = 4000
+	// MaxContentTokens is the max token budget of the search with content tool
+	MaxContentTokens = 50000
+
+	// minChunkChars is the smallest chunk the pages are split into when the budget is small
+	minChunkChars = 200
+)
+
+// TavilySearchWithContentHandler is the handler for the search with content tool, it searches with the raw content of the pages,
+// then returns the chunks of each page most relevant to the query within the token or character budget
+func TavilySearchWithContentHandler(client TavilyClient, tools *Tools) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var query string
+		if err := param.Assign(&query, request.GetArguments()["query"]); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("query error: %v", err)), nil
+		}
+		budget, err := contentBudget(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		result, err := client.Search(
+			ctx,
+			query,
+			tavily.WithOption("topic", tools.argument(request, "topic")),
+			tavily.WithOption("days", tools.argument(request, "days")),
+			tavily.WithOption("limit", tools.argument(request, "limit")),
+			tavily.WithOption("search_depth", tools.argument(request, "search_depth")),
+			tavily.WithOption("include_domains", request.GetArguments()["include_domains"]),
+			tavily.WithOption("exclude_domains", request.GetArguments()["exclude_domains"]),
+			tavily.WithRawContent(true),
+		)
+
+		if err != nil {
+			return toolError(err), nil
+		}
+
+		if len(result.Results) == 0 {
+			recordResults(ctx, 0)
+			return mcp.NewToolResultError(fmt.Sprintf("no pages found for query: %s", query)), nil
+		}
+
+		// the headers of the sources are part of the budget, the least relevant pages are left out
+		// until each page has room for a chunk
+		pages := result.Results[:fitSources(result.Results, budget)]
+		if len(pages) == 0 {
+			return mcp.NewToolResultError("budget error: the budget is too small for a single source, raise max_tokens or max_chars"), nil
+		}
+		headers := make([]string, len(pages))
+		for i, page := range pages {
+			headers[i] = sourceHeader(i+1, page)
+			budget -= utf8.RuneCountInString(headers[i]) + utf8.RuneCountInString(sourceFooter(i+1))
+		}
+
+		size := min(DefaultChunkChars, max(budget/len(pages), minChunkChars))
+		texts := make([]string, len(pages))
+		for i, page := range pages {
+			texts[i] = page.Content
+			if page.RawContent != nil && strings.TrimSpace(*page.RawContent) != "" {
+				texts[i] = *page.RawContent
+			}
+		}
+		repeated := repeatedLines(texts)
+		var chunks []chunk
+		for i, text := range texts {
+			for j, c := range chunkParagraphs(stripBoilerplate(text, repeated), size) {
+				chunks = append(chunks, chunk{source: i, index: j, text: c})
+			}
+		}
+		rankChunks(query, chunks)
+		picked := selectChunks(chunks, budget)
+
+		contents := make([]mcp.Content, 0, len(pages)+1)
+		sources := 0
+		for i := range pages {
+			var own []chunk
+			for _, c := range picked {
+				if c.source == i {
+					own = append(own, c)
+				}
+			}
+			if len(own) == 0 {
+				continue
+			}
+			sources++
+			contents = append(contents, mcp.NewTextContent(headers[i]+joinChunks(own)+sourceFooter(i+1)))
+		}
+
+		recordResults(ctx, sources)
+		if len(picked) < len(chunks) || len(pages) < len(result.Results) {
+			contents = append(contents, mcp.NewTextContent(fmt.Sprintf(
+				"note: %d of %d chunks of %d of %d pages are kept within the budget, the chunks and pages less relevant to the query are left out.",
+				len(picked), len(chunks), len(pages), len(result.Results))))
+		}
+
+		res := searchResult(result)
+		res.Meta.AdditionalFields["chunks"] = len(picked)
+		res.Meta.AdditionalFields["chunks_total"] = len(chunks)
+		return &mcp.CallToolResult{
+			Result:  res,
+			Content: append(contents, downgradeNote(result)...),
+		}, nil
+	}
+}
+
+// contentBudget return the budget of characters of the call, the smaller of max_tokens and max_chars if both are set
+func contentBudget(request mcp.CallToolRequest) (int, error) {
+	tokens := DefaultContentTokens
+	if v, ok := request.GetArguments()["max_tokens"]; ok && v != nil {
+		if err := param.Assign(&tokens, v); err != nil {
+			return 0, fmt.Errorf("max_tokens error: %v", err)
+		}
+		if tokens < 100 || tokens > MaxContentTokens {
+			return 0, fmt.Errorf("max_tokens error: %d must between 100 and %d", tokens, MaxContentTokens)
+		}
+	}
+	budget := tokens * charsPerToken
+	if v, ok := request.GetArguments()["max_chars"]; ok && v != nil {
+		var chars int
+		if err := param.Assign(&chars, v); err != nil {
+			return 0, fmt.Errorf("max_chars error: %v", err)
+		}
+		if chars < 400 || chars > MaxContentTokens*charsPerToken {
+			return 0, fmt.Errorf("max_chars error: %d must between 400 and %d", chars, MaxContentTokens*charsPerToken)
+		}
+		budget = min(budget, chars)
+	}
+	return budget, nil
+}
+
+// fitSources return how many of the pages, in the order of relevance, fit in the budget of characters
+// with their header, their footer and room for a chunk each
+func fitSources(pages []tavily.TavilySearchResult, budget int) int {
+	used := 0
+	for i, page := range pages {
+		used += utf8.RuneCountInString(sourceHeader(i+1, page)) + utf8.RuneCountInString(sourceFooter(i+1)) + minChunkChars
+		if used > budget {
+			return i
+		}
+	}
+	return len(pages)
+}
+
+// sourceHeader is the line opening the content of a source
+func sourceHeader(n int, page tavily.TavilySearchResult) string {
+	header := fmt.Sprintf("[Source %d] 《%s》\nURL: %s\n", n, strings.TrimSpace(page.Title), page.URL)
+	if page.PublishedDate != nil && *page.PublishedDate != "" {
+		header += fmt.Sprintf("Published: %s\n", *page.PublishedDate)
+	}
+	return header + "---\n"
+}
+
+// sourceFooter is the line closing the content of a source
+func sourceFooter(n int) string {
+	return fmt.Sprintf("\n[End of source %d]", n)
+}
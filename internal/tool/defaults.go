@@ -0,0 +1,128 @@
+package tool
+
+import (
+	"fmt"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+const (
+	SearchNewsToolName      = "search_news"
+	SearchNewsImageToolName = "search_news_image"
+	SearchAnswerToolName    = "search_answer"
+	ExtractURLToolName      = "extract_url"
+	SearchContentToolName   = "search_with_content"
+	WebSearchToolName       = "web_search"
+)
+
+// Defaults is the default arguments of a tool, used when the call omits them, zero values are unset
+type Defaults struct {
+	Days        int
+	Limit       int
+	SearchDepth string
+	Topic       string
+}
+
+// builtinDefaults is the default arguments of the search tools
+var builtinDefaults = map[string]Defaults{
+	SearchNewsToolName: {
+		Days:        tavily.DefaultDays,
+		Limit:       NewsSearchReferencesLimit,
+		SearchDepth: tavily.DepthBasic,
+		Topic:       tavily.TopicNews,
+	},
+	SearchNewsImageToolName: {
+		Days:        tavily.DefaultDays,
+		Limit:       ImageSearchReferencesLimit,
+		SearchDepth: tavily.DepthBasic,
+		Topic:       tavily.TopicNews,
+	},
+	SearchAnswerToolName: {
+		Days:        tavily.DefaultDays,
+		Limit:       AnswerSourcesLimit,
+		SearchDepth: tavily.DepthBasic,
+		Topic:       tavily.TopicGeneral,
+	},
+	WebSearchToolName: {
+		Days:        tavily.DefaultDays,
+		Limit:       WebSearchResultsLimit,
+		SearchDepth: tavily.DepthBasic,
+		Topic:       tavily.TopicGeneral,
+	},
+	SearchContentToolName: {
+		Days:        tavily.DefaultDays,
+		Limit:       ContentSourcesLimit,
+		SearchDepth: tavily.DepthBasic,
+		Topic:       tavily.TopicGeneral,
+	},
+}
+
+// mergeDefaults override the builtin defaults of the tools, unset fields keep the builtin defaults
+func mergeDefaults(defaults map[string]Defaults) (map[string]Defaults, error) {
+	merged := make(map[string]Defaults, len(builtinDefaults))
+	for name, d := range builtinDefaults {
+		merged[name] = d
+	}
+	for name, d := range defaults {
+		if err := ValidateDefaults(name, d); err != nil {
+			return nil, err
+		}
+		base := merged[name]
+		if d.Days != 0 {
+			base.Days = d.Days
+		}
+		if d.Limit != 0 {
+			base.Limit = d.Limit
+		}
+		if d.SearchDepth != "" {
+			base.SearchDepth = d.SearchDepth
+		}
+		if d.Topic != "" {
+			base.Topic = d.Topic
+		}
+		merged[name] = base
+	}
+	return merged, nil
+}
+
+// ValidateDefaults check the tool has default arguments and the set fields are valid
+func ValidateDefaults(name string, d Defaults) error {
+	if _, ok := builtinDefaults[name]; !ok {
+		return fmt.Errorf("tool %s has no default arguments", name)
+	}
+	if err := d.validate(); err != nil {
+		return fmt.Errorf("tool %s defaults error: %w", name, err)
+	}
+	return nil
+}
+
+func (d Defaults) validate() error {
+	if d.Days != 0 && (d.Days < 1 || d.Days > 30) {
+		return fmt.Errorf("days %d must between 1 and 30", d.Days)
+	}
+	if d.Limit != 0 && (d.Limit < 1 || d.Limit > 20) {
+		return fmt.Errorf("limit %d must between 1 and 20", d.Limit)
+	}
+	if d.SearchDepth != "" && d.SearchDepth != tavily.DepthBasic && d.SearchDepth != tavily.DepthAdvanced {
+		return fmt.Errorf("%s is not a valid search depth", d.SearchDepth)
+	}
+	if d.Topic != "" && d.Topic != tavily.TopicGeneral && d.Topic != tavily.TopicNews {
+		return fmt.Errorf("%s is not a valid topic", d.Topic)
+	}
+	return nil
+}
+
+// get return the default of the argument, nil if unset
+func (d Defaults) get(key string) any {
+	switch {
+	case key == "days" && d.Days != 0:
+		return d.Days
+	case key == "limit" && d.Limit != 0:
+		return d.Limit
+	case key == "search_depth" && d.SearchDepth != "":
+		return d.SearchDepth
+	case key == "topic" && d.Topic != "":
+		return d.Topic
+	}
+	return nil
+}
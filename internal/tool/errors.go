@@ -0,0 +1,60 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+// toolError turn the tavily error into a tool error result, tell the model what happened and whether retrying helps
+func toolError(err error) *mcp.CallToolResult {
+	var (
+		authErr       *tavily.AuthError
+		rateLimitErr  *tavily.RateLimitError
+		quotaErr      *tavily.QuotaError
+		badRequestErr *tavily.BadRequestError
+		upstreamErr   *tavily.UpstreamError
+		networkErr    *tavily.NetworkError
+		coolingErr    *tavily.KeysCoolingDownError
+		budgetErr     *tavily.BudgetExceededError
+	)
+
+	switch {
+	case errors.As(err, &authErr):
+		return mcp.NewToolResultError(fmt.Sprintf("tavily rejected the api key of the server (status %d): %s. Retrying will not help, ask the user to check the server configuration.", authErr.StatusCode, authErr.Message))
+	case errors.As(err, &rateLimitErr):
+		if rateLimitErr.RetryAfter > 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("tavily rate limit reached: %s. Retry after %d seconds.", rateLimitErr.Message, int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))))
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("tavily rate limit reached: %s. Wait a moment before searching again.", rateLimitErr.Message))
+	case errors.As(err, &quotaErr):
+		return mcp.NewToolResultError(fmt.Sprintf("tavily credits quota of the server is exhausted (status %d): %s. Further searches will fail until the quota is reset, do not retry.", quotaErr.StatusCode, quotaErr.Message))
+	case errors.As(err, &badRequestErr):
+		return mcp.NewToolResultError(fmt.Sprintf("tavily rejected the search arguments: %s. Fix the arguments and try again.", badRequestErr.Message))
+	case errors.As(err, &upstreamErr):
+		return mcp.NewToolResultError(fmt.Sprintf("tavily service is unavailable (status %d): %s. Try again later.", upstreamErr.StatusCode, upstreamErr.Message))
+	case errors.As(err, &coolingErr):
+		return mcp.NewToolResultError(fmt.Sprintf("all tavily api keys of the server are cooling down after auth, quota or rate limit errors. Retry after %d seconds.", int(math.Ceil(coolingErr.RetryAfter.Seconds()))))
+	case errors.As(err, &budgetErr):
+		left := max(budgetErr.Cap-budgetErr.Used, 0)
+		text := fmt.Sprintf("the %s tavily credit budget of the server is exhausted: this call needs %d credits, %d of %d are left. It resets at %s, do not retry before that.",
+			budgetErr.Period, budgetErr.Credits, left, budgetErr.Cap, budgetErr.ResetAt.Format(time.RFC3339))
+		if left > 0 {
+			text += " A basic search depth or fewer urls cost fewer credits."
+		}
+		return mcp.NewToolResultError(text)
+	case errors.As(err, &networkErr):
+		return mcp.NewToolResultError(fmt.Sprintf("failed to reach tavily: %v. Try again later.", networkErr.Err))
+	case errors.Is(err, context.DeadlineExceeded):
+		return mcp.NewToolResultError("tavily request timed out. Try again later, or with a basic search depth.")
+	case errors.Is(err, context.Canceled):
+		return mcp.NewToolResultError("tavily request is canceled.")
+	default:
+		return mcp.NewToolResultError(err.Error())
+	}
+}
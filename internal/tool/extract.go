@@ -0,0 +1,61 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/y7ut/mcp-tavily-search/pkg/param"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+// TavilyExtractHandler is the handler for the extract tool, return the raw content of each url
+func TavilyExtractHandler(client TavilyClient) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var urls []string
+		if err := param.Assign(&urls, request.GetArguments()["urls"]); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("urls error: %v", err)), nil
+		}
+
+		result, err := client.Extract(
+			ctx,
+			urls,
+			tavily.WithOption("extract_depth", request.GetArguments()["extract_depth"]),
+			tavily.WithOption("include_images", request.GetArguments()["include_images"]),
+		)
+
+		if err != nil {
+			return toolError(err), nil
+		}
+
+		recordResults(ctx, len(result.Results))
+		if len(result.Results) == 0 && len(result.FailedResults) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("no content extracted for urls: %s", strings.Join(urls, ", "))), nil
+		}
+
+		textContents := make([]mcp.Content, 0, len(result.Results)+len(result.FailedResults))
+		for _, page := range result.Results {
+			text := fmt.Sprintf("%s\n %s", page.URL, page.RawContent)
+			if len(page.Images) > 0 {
+				text = fmt.Sprintf("%s\n Images:\n %s", text, strings.Join(page.Images, "\n "))
+			}
+			textContents = append(textContents, mcp.TextContent{
+				Type: "text",
+				Text: text,
+			})
+		}
+		for _, failed := range result.FailedResults {
+			textContents = append(textContents, mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("failed to extract %s: %s", failed.URL, failed.Error),
+			})
+		}
+
+		return &mcp.CallToolResult{
+			Content: textContents,
+			IsError: len(result.Results) == 0,
+		}, nil
+	}
+}
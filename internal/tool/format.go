@@ -0,0 +1,99 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/y7ut/mcp-tavily-search/pkg/param"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+const (
+	OutputText     = "text"
+	OutputMarkdown = "markdown"
+	OutputJSON     = "json"
+)
+
+// searchOutputSchema describe the json output of the search tools, it is part of the tool description
+const searchOutputSchema = `{"query": string, "answer": string (only if generated), "response_time": number, ` +
+	`"results": [{"title": string, "url": string, "content": string, "score": number, "published_date": string | null, "raw_content": string | null}], ` +
+	`"images": [{"url": string, "description": string}] (only if requested)}`
+
+// searchOutput is the json output of the search tools
+type searchOutput struct {
+	Query        string                      `json:"query"`
+	Answer       *string                     `json:"answer,omitempty"`
+	ResponseTime float64                     `json:"response_time"`
+	Results      []tavily.TavilySearchResult `json:"results"`
+	Images       []tavily.TavilySearchImage  `json:"images,omitempty"`
+}
+
+// outputFormat return the output_format argument of the call, default is text
+func outputFormat(request mcp.CallToolRequest) (string, error) {
+	format := OutputText
+	if v, ok := request.GetArguments()["output_format"]; ok && v != nil {
+		if err := param.Assign(&format, v); err != nil {
+			return "", err
+		}
+	}
+	switch format {
+	case OutputText, OutputMarkdown, OutputJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("%s is not a valid output format, use %s, %s or %s", format, OutputText, OutputMarkdown, OutputJSON)
+}
+
+// searchContents format the search results as the contents of the tool result,
+// json also returns the results as structured content, the text content carries the same json for the clients without it
+func searchContents(result *tavily.TavilySearchResponse, format string) (contents []mcp.Content, structured any) {
+	switch format {
+	case OutputJSON:
+		output := searchOutput{
+			Query:        result.Query,
+			Answer:       result.Answer,
+			ResponseTime: result.ResponseTime,
+			Results:      result.Results,
+			Images:       result.Images,
+		}
+		b, err := json.Marshal(output)
+		if err != nil {
+			return []mcp.Content{mcp.NewTextContent(fmt.Sprintf("json output error: %v", err))}, nil
+		}
+		return []mcp.Content{mcp.NewTextContent(string(b))}, output
+	case OutputMarkdown:
+		contents = make([]mcp.Content, len(result.Results))
+		for i, news := range result.Results {
+			contents[i] = mcp.NewTextContent(markdownResult(news))
+		}
+		return contents, nil
+	default:
+		contents = make([]mcp.Content, len(result.Results))
+		for i, news := range result.Results {
+			text := fmt.Sprintf("《%s》: %s\n %s", news.Title, news.URL, news.Content)
+			if news.RawContent != nil && *news.RawContent != "" {
+				text += "\n\n" + *news.RawContent
+			}
+			contents[i] = mcp.TextContent{
+				Type: "text",
+				Text: text,
+			}
+		}
+		return contents, nil
+	}
+}
+
+// markdownResult format the search result as a markdown section with the published date and the score
+func markdownResult(news tavily.TavilySearchResult) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "### [%s](%s)\n", strings.TrimSpace(news.Title), news.URL)
+	if news.PublishedDate != nil && *news.PublishedDate != "" {
+		fmt.Fprintf(&text, "*Published: %s* · ", *news.PublishedDate)
+	}
+	fmt.Fprintf(&text, "*Score: %.2f*\n\n%s", news.Score, strings.TrimSpace(news.Content))
+	if news.RawContent != nil && *news.RawContent != "" {
+		fmt.Fprintf(&text, "\n\n#### Full content\n\n%s", strings.TrimSpace(*news.RawContent))
+	}
+	return text.String()
+}
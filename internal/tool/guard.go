@@ -0,0 +1,227 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultMaxRedirects is the max redirects followed when downloading an image
+const DefaultMaxRedirects = 3
+
+// blockedPrefixes is the ip ranges images are never fetched from, besides loopback, private,
+// link-local (including the cloud metadata 169.254.169.254), multicast and unspecified addresses
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),          // this network
+	netip.MustParsePrefix("100.64.0.0/10"),      // carrier-grade nat
+	netip.MustParsePrefix("192.0.0.0/24"),       // ietf protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),      // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),        // reserved and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),       // nat64, may embed an internal ipv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),     // local-use nat64
+	netip.MustParsePrefix("2001:db8::/32"),      // documentation
+	netip.MustParsePrefix("fd00:ec2::/32"),      // aws metadata over ipv6
+	netip.MustParsePrefix("100::/64"),           // discard-only
+	netip.MustParsePrefix("2002::/16"),          // 6to4, may embed an internal ipv4
+	netip.MustParsePrefix("255.255.255.255/32"), // broadcast
+}
+
+// DefaultURLPolicy
+func DefaultURLPolicy() URLPolicy {
+	return URLPolicy{MaxRedirects: DefaultMaxRedirects}
+}
+
+// URLPolicy is the rules of the urls images are fetched from
+type URLPolicy struct {
+	// Allow is the hosts and cidrs allowed even if they resolve to a blocked ip range,
+	// a host starting with a dot matches all its subdomains, like .example.com
+	Allow []string
+	// Deny is the hosts and cidrs always rejected
+	Deny []string
+	// MaxRedirects is the max redirects followed
+	MaxRedirects int
+}
+
+// urlGuard check the urls and the dialed ips by the policy
+type urlGuard struct {
+	allowHosts    []string
+	allowPrefixes []netip.Prefix
+	denyHosts     []string
+	denyPrefixes  []netip.Prefix
+	maxRedirects  int
+	resolver      *net.Resolver
+}
+
+func newURLGuard(policy URLPolicy) (*urlGuard, error) {
+	g := &urlGuard{maxRedirects: policy.MaxRedirects, resolver: net.DefaultResolver}
+	if g.maxRedirects < 0 {
+		g.maxRedirects = 0
+	}
+	var err error
+	if g.allowHosts, g.allowPrefixes, err = parseHostList(policy.Allow); err != nil {
+		return nil, fmt.Errorf("image allow list error: %v", err)
+	}
+	if g.denyHosts, g.denyPrefixes, err = parseHostList(policy.Deny); err != nil {
+		return nil, fmt.Errorf("image deny list error: %v", err)
+	}
+	return g, nil
+}
+
+// parseHostList split the list into hosts and ip prefixes, a single ip is a /32 or /128 prefix
+func parseHostList(list []string) ([]string, []netip.Prefix, error) {
+	var hosts []string
+	var prefixes []netip.Prefix
+	for _, item := range list {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s is not a valid cidr", item)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(item); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		hosts = append(hosts, item)
+	}
+	return hosts, prefixes, nil
+}
+
+// client build the http client fetching through the guard
+func (g *urlGuard) client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		// never go through a proxy, the guard must see the real destination
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return g.dial(ctx, dialer, network, addr)
+		},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > g.maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", g.maxRedirects)
+			}
+			return g.checkURL(req.URL)
+		},
+	}
+}
+
+// checkURL accept only the http(s) urls whose host is not denied
+func (g *urlGuard) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url scheme %q is not allowed", u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return errors.New("url host is empty")
+	}
+	if matchHost(g.denyHosts, host) {
+		return fmt.Errorf("host %s is denied", host)
+	}
+	return nil
+}
+
+// dial resolve the host, check every ip, then dial the checked ip, so dns rebinding can not bypass the guard
+func (g *urlGuard) dial(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	host = strings.ToLower(host)
+	if matchHost(g.denyHosts, host) {
+		return nil, fmt.Errorf("host %s is denied", host)
+	}
+	hostAllowed := matchHost(g.allowHosts, host)
+
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else {
+		ips, err := g.resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, err
+		}
+		addrs = ips
+	}
+
+	var lastErr error = fmt.Errorf("no address found for %s", host)
+	for _, ip := range addrs {
+		ip = ip.Unmap()
+		if err := g.checkIP(ip, hostAllowed); err != nil {
+			return nil, fmt.Errorf("host %s: %v", host, err)
+		}
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// checkIP reject the denied ips, and the blocked ip ranges unless they are allowed
+func (g *urlGuard) checkIP(ip netip.Addr, hostAllowed bool) error {
+	for _, prefix := range g.denyPrefixes {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("ip %s is denied", ip)
+		}
+	}
+	if hostAllowed || !isBlockedIP(ip) {
+		return nil
+	}
+	for _, prefix := range g.allowPrefixes {
+		if prefix.Contains(ip) {
+			return nil
+		}
+	}
+	return fmt.Errorf("ip %s is in a private or reserved range", ip)
+}
+
+func isBlockedIP(ip netip.Addr) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost match the host by the list, item starting with a dot matches the domain and its subdomains
+func matchHost(list []string, host string) bool {
+	for _, item := range list {
+		if strings.HasPrefix(item, ".") {
+			if host == item[1:] || strings.HasSuffix(host, item) {
+				return true
+			}
+			continue
+		}
+		if host == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,588 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily/tavilytest"
+)
+
+// mockClient is a TavilyClient returning the canned responses, it records the arguments of the last call
+type mockClient struct {
+	resp    *tavily.TavilySearchResponse
+	extract *tavily.TavilyExtractResponse
+	err     error
+	query   string
+	urls    []string
+	mode    tavily.AnswerMode
+	options *tavily.OptionManager
+}
+
+// apply record the options of the call, it returns the error of the invalid ones
+func (m *mockClient) apply(h []tavily.WithOptionHelper) error {
+	m.options = tavily.NewOptionManager()
+	for _, helper := range h {
+		helper(m.options)
+	}
+	return m.options.Err()
+}
+
+func (m *mockClient) search(query string, h []tavily.WithOptionHelper) (*tavily.TavilySearchResponse, error) {
+	m.query = query
+	if err := m.apply(h); err != nil {
+		return nil, err
+	}
+	return m.resp, m.err
+}
+
+func (m *mockClient) Search(_ context.Context, query string, h ...tavily.WithOptionHelper) (*tavily.TavilySearchResponse, error) {
+	return m.search(query, h)
+}
+
+func (m *mockClient) SearchImage(_ context.Context, query string, h ...tavily.WithOptionHelper) (*tavily.TavilySearchResponse, error) {
+	return m.search(query, h)
+}
+
+func (m *mockClient) SearchAnswer(_ context.Context, query string, mode tavily.AnswerMode, h ...tavily.WithOptionHelper) (*tavily.TavilySearchResponse, error) {
+	m.mode = mode
+	return m.search(query, h)
+}
+
+func (m *mockClient) Extract(_ context.Context, urls []string, h ...tavily.WithOptionHelper) (*tavily.TavilyExtractResponse, error) {
+	m.urls = urls
+	if err := m.apply(h); err != nil {
+		return nil, err
+	}
+	return m.extract, m.err
+}
+
+func (m *mockClient) KeyUsage() []tavily.KeyUsage {
+	return nil
+}
+
+func newTools(t *testing.T, defaults map[string]Defaults) *Tools {
+	t.Helper()
+	opts := DefaultOptions()
+	opts.Defaults = defaults
+	tools, err := NewTools(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tools
+}
+
+func callRequest(name string, args map[string]any) mcp.CallToolRequest {
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+	return req
+}
+
+func callText(res *mcp.CallToolResult) string {
+	var texts []string
+	for _, content := range res.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func TestSearchHandler(t *testing.T) {
+	resp := &tavily.TavilySearchResponse{
+		Query: "golang",
+		Results: []tavily.TavilySearchResult{
+			{Title: "Go 1.24 released", URL: "https://go.dev/blog/go1.24", Content: "The Go team released Go 1.24."},
+		},
+	}
+	// the server overrides the defaults of search_news, the other server keeps the builtin ones
+	custom := newTools(t, map[string]Defaults{SearchNewsToolName: {Limit: 7, Topic: tavily.TopicGeneral}})
+	builtin := newTools(t, nil)
+
+	tests := []struct {
+		name      string
+		tools     *Tools
+		args      map[string]any
+		err       error
+		wantError bool
+		wantText  string
+		want      map[string]any
+	}{
+		{
+			name:     "server defaults",
+			tools:    custom,
+			args:     map[string]any{"keyword": "golang"},
+			wantText: "Go 1.24 released",
+			want:     map[string]any{tavily.OptionMaxResults: 7, tavily.OptionTopic: tavily.TopicGeneral, tavily.OptionDays: tavily.DefaultDays},
+		},
+		{
+			name:     "builtin defaults",
+			tools:    builtin,
+			args:     map[string]any{"keyword": "golang"},
+			wantText: "Go 1.24 released",
+			want:     map[string]any{tavily.OptionMaxResults: NewsSearchReferencesLimit, tavily.OptionTopic: tavily.TopicNews},
+		},
+		{
+			name:     "arguments override the defaults",
+			tools:    custom,
+			args:     map[string]any{"keyword": "golang", "limit": 2, "topic": tavily.TopicNews},
+			wantText: "Go 1.24 released",
+			want:     map[string]any{tavily.OptionMaxResults: 2, tavily.OptionTopic: tavily.TopicNews},
+		},
+		{
+			name:      "invalid argument",
+			tools:     builtin,
+			args:      map[string]any{"keyword": "golang", "search_depth": "deep"},
+			wantError: true,
+			wantText:  "not a valid search depth",
+		},
+		{
+			name:      "tavily error",
+			tools:     builtin,
+			args:      map[string]any{"keyword": "golang"},
+			err:       &tavily.UpstreamError{APIError: tavily.APIError{StatusCode: 503, Message: "unavailable"}},
+			wantError: true,
+			wantText:  "Try again later",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockClient{resp: resp, err: tt.err}
+			handler := tt.tools.withDefaults(TavilySearchHandler(client, tt.tools))
+			res, err := handler(context.Background(), callRequest(SearchNewsToolName, tt.args))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.IsError != tt.wantError {
+				t.Fatalf("got error %v, want %v: %s", res.IsError, tt.wantError, callText(res))
+			}
+			if text := callText(res); !strings.Contains(text, tt.wantText) {
+				t.Errorf("got %q, want it to contain %q", text, tt.wantText)
+			}
+			for key, want := range tt.want {
+				if got, _ := client.options.GetOption(key); got != want {
+					t.Errorf("got option %s %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewTools(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Options)
+		wantErr string
+	}{
+		{name: "defaults", modify: func(*Options) {}},
+		{name: "bad url policy", modify: func(o *Options) { o.URLPolicy.Allow = []string{"10.0.0.0/99"} }, wantErr: "not a valid cidr"},
+		{name: "bad image format", modify: func(o *Options) { o.Image.Format = "tiff" }, wantErr: "not supported"},
+		{name: "unknown tool", modify: func(o *Options) { o.Defaults = map[string]Defaults{"nope": {Limit: 1}} }, wantErr: "has no default arguments"},
+		{name: "bad default", modify: func(o *Options) { o.Defaults = map[string]Defaults{SearchNewsToolName: {Days: 90}} }, wantErr: "days 90"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			tt.modify(&opts)
+			_, err := NewTools(opts)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSearchImageHandler(t *testing.T) {
+	fake := tavilytest.NewServer()
+	defer fake.Close()
+	opts := DefaultOptions()
+	opts.URLPolicy.Allow = []string{"127.0.0.1"}
+	tools, err := NewTools(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := func(url string, n int) tavily.TavilySearchImage {
+		return tavily.TavilySearchImage{URL: url, Description: fmt.Sprintf("Image %d of golang.", n)}
+	}
+	missing := fake.URL + "/images/missing.png"
+
+	tests := []struct {
+		name      string
+		resp      *tavily.TavilySearchResponse
+		err       error
+		images    int
+		wantError bool
+		wantText  string
+	}{
+		{
+			name:     "all images",
+			resp:     &tavily.TavilySearchResponse{Images: []tavily.TavilySearchImage{image(fake.ImageURL(1), 1), image(fake.ImageURL(2), 2), image(fake.ImageURL(3), 3)}},
+			images:   3,
+			wantText: "Image 3 of golang.",
+		},
+		{
+			name:     "failed download",
+			resp:     &tavily.TavilySearchResponse{Images: []tavily.TavilySearchImage{image(fake.ImageURL(1), 1), image(missing, 2)}},
+			images:   1,
+			wantText: "failed to download image 2",
+		},
+		{
+			name:      "all downloads failed",
+			resp:      &tavily.TavilySearchResponse{Images: []tavily.TavilySearchImage{image(missing, 1)}},
+			wantError: true,
+			wantText:  "failed to download image 1",
+		},
+		{
+			name:      "no images",
+			resp:      &tavily.TavilySearchResponse{Results: []tavily.TavilySearchResult{{Title: "Go", URL: "https://go.dev"}}},
+			wantError: true,
+			wantText:  "no news found",
+		},
+		{
+			name:      "tavily error",
+			err:       &tavily.AuthError{APIError: tavily.APIError{StatusCode: 401, Message: "invalid key"}},
+			wantError: true,
+			wantText:  "rejected the api key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockClient{resp: tt.resp, err: tt.err}
+			res, err := TavilySearchImageHandler(client, tools)(context.Background(), callRequest(SearchNewsImageToolName, map[string]any{"keyword": "golang"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.IsError != tt.wantError {
+				t.Fatalf("got error %v, want %v: %s", res.IsError, tt.wantError, callText(res))
+			}
+			if text := callText(res); !strings.Contains(text, tt.wantText) {
+				t.Errorf("got %q, want it to contain %q", text, tt.wantText)
+			}
+			images := 0
+			for _, content := range res.Content {
+				switch content.(type) {
+				case mcp.ImageContent, *mcp.ImageContent:
+					images++
+				}
+			}
+			if images != tt.images {
+				t.Errorf("got %d images, want %d", images, tt.images)
+			}
+		})
+	}
+}
+
+func TestSearchAnswerHandler(t *testing.T) {
+	answer := "Go is a programming language."
+	resp := &tavily.TavilySearchResponse{
+		Query:  "what is go",
+		Answer: &answer,
+		Results: []tavily.TavilySearchResult{
+			{Title: "The Go Programming Language", URL: "https://go.dev"},
+			{Title: "Go (programming language)", URL: "https://en.wikipedia.org/wiki/Go_(programming_language)"},
+		},
+	}
+	tools := newTools(t, nil)
+
+	tests := []struct {
+		name      string
+		args      map[string]any
+		resp      *tavily.TavilySearchResponse
+		err       error
+		wantError bool
+		wantText  string
+		wantMode  tavily.AnswerMode
+	}{
+		{
+			name:     "answer and sources",
+			args:     map[string]any{"question": "what is go"},
+			resp:     resp,
+			wantText: answer + "\n\nSources:\n1. 《The Go Programming Language》: https://go.dev\n2. ",
+			wantMode: tavily.AnswerBasic,
+		},
+		{
+			name:     "advanced mode",
+			args:     map[string]any{"question": "what is go", "answer_mode": "advanced"},
+			resp:     resp,
+			wantText: answer,
+			wantMode: tavily.AnswerAdvanced,
+		},
+		{
+			name:      "invalid mode",
+			args:      map[string]any{"question": "what is go", "answer_mode": "long"},
+			wantError: true,
+			wantText:  "not a valid answer mode",
+		},
+		{
+			name:      "no answer",
+			args:      map[string]any{"question": "what is go"},
+			resp:      &tavily.TavilySearchResponse{Results: resp.Results},
+			wantError: true,
+			wantText:  "no answer generated",
+			wantMode:  tavily.AnswerBasic,
+		},
+		{
+			name:      "tavily error",
+			args:      map[string]any{"question": "what is go"},
+			err:       &tavily.QuotaError{APIError: tavily.APIError{StatusCode: 432, Message: "plan limit exceeded"}},
+			wantError: true,
+			wantText:  "quota of the server is exhausted",
+			wantMode:  tavily.AnswerBasic,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockClient{resp: tt.resp, err: tt.err}
+			res, err := TavilySearchAnswerHandler(client, tools)(context.Background(), callRequest(SearchAnswerToolName, tt.args))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.IsError != tt.wantError {
+				t.Fatalf("got error %v, want %v: %s", res.IsError, tt.wantError, callText(res))
+			}
+			if text := callText(res); !strings.Contains(text, tt.wantText) {
+				t.Errorf("got %q, want it to contain %q", text, tt.wantText)
+			}
+			if client.mode != tt.wantMode {
+				t.Errorf("got answer mode %q, want %q", client.mode, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestExtractHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		extract   *tavily.TavilyExtractResponse
+		err       error
+		wantError bool
+		wantText  string
+	}{
+		{
+			name: "pages and failures",
+			extract: &tavily.TavilyExtractResponse{
+				Results:       []tavily.TavilyExtractResult{{URL: "https://go.dev", RawContent: "Build simple, secure, scalable systems with Go.", Images: []string{"https://go.dev/images/gophers.png"}}},
+				FailedResults: []tavily.TavilyExtractFailedResult{{URL: "https://go.dev/missing", Error: "not found"}},
+			},
+			wantText: "https://go.dev\n Build simple, secure, scalable systems with Go.\n Images:\n https://go.dev/images/gophers.png\nfailed to extract https://go.dev/missing: not found",
+		},
+		{
+			name:      "all failed",
+			extract:   &tavily.TavilyExtractResponse{FailedResults: []tavily.TavilyExtractFailedResult{{URL: "https://go.dev", Error: "timeout"}}},
+			wantError: true,
+			wantText:  "failed to extract https://go.dev: timeout",
+		},
+		{
+			name:      "nothing extracted",
+			extract:   &tavily.TavilyExtractResponse{},
+			wantError: true,
+			wantText:  "no content extracted",
+		},
+		{
+			name:      "tavily error",
+			err:       &tavily.RateLimitError{APIError: tavily.APIError{StatusCode: 429, Message: "too many requests"}},
+			wantError: true,
+			wantText:  "rate limit reached",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockClient{extract: tt.extract, err: tt.err}
+			args := map[string]any{"urls": []any{"https://go.dev", "https://go.dev/missing"}, "extract_depth": tavily.DepthAdvanced}
+			res, err := TavilyExtractHandler(client)(context.Background(), callRequest(ExtractURLToolName, args))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.IsError != tt.wantError {
+				t.Fatalf("got error %v, want %v: %s", res.IsError, tt.wantError, callText(res))
+			}
+			if text := callText(res); !strings.Contains(text, tt.wantText) {
+				t.Errorf("got %q, want it to contain %q", text, tt.wantText)
+			}
+			if len(client.urls) != 2 {
+				t.Errorf("got urls %v, want the 2 urls of the call", client.urls)
+			}
+			if got, _ := client.options.GetOption(tavily.OptionExtractDepth); got != tavily.DepthAdvanced {
+				t.Errorf("got extract depth %v, want %s", got, tavily.DepthAdvanced)
+			}
+		})
+	}
+}
+
+func TestWebSearchHandler(t *testing.T) {
+	answer := "Go 1.24 is the latest release."
+	resp := &tavily.TavilySearchResponse{
+		Query:   "golang",
+		Answer:  &answer,
+		Results: []tavily.TavilySearchResult{{Title: "Go 1.24 released", URL: "https://go.dev/blog/go1.24", Content: "The Go team released Go 1.24."}},
+		Images:  []tavily.TavilySearchImage{{URL: "https://go.dev/images/gophers.png", Description: "The gophers."}},
+	}
+	tools := newTools(t, map[string]Defaults{WebSearchToolName: {Limit: 8}})
+
+	tests := []struct {
+		name      string
+		args      map[string]any
+		resp      *tavily.TavilySearchResponse
+		err       error
+		wantError bool
+		wantText  string
+		wantLimit any
+		json      bool
+	}{
+		{
+			name:      "answer, results and images",
+			args:      map[string]any{"query": "golang"},
+			resp:      resp,
+			wantText:  "Answer: " + answer + "\n《Go 1.24 released》",
+			wantLimit: 8,
+		},
+		{
+			name:      "image list",
+			args:      map[string]any{"query": "golang", "max_results": 3},
+			resp:      resp,
+			wantText:  "Images:\n1. https://go.dev/images/gophers.png\n   The gophers.",
+			wantLimit: 3,
+		},
+		{
+			name:      "json",
+			args:      map[string]any{"query": "golang", "output_format": "json"},
+			resp:      resp,
+			wantText:  `"answer":"Go 1.24 is the latest release."`,
+			wantLimit: 8,
+			json:      true,
+		},
+		{
+			name:      "invalid output format",
+			args:      map[string]any{"query": "golang", "output_format": "yaml"},
+			wantError: true,
+			wantText:  "output_format error",
+		},
+		{
+			name:      "no results",
+			args:      map[string]any{"query": "golang"},
+			resp:      &tavily.TavilySearchResponse{Query: "golang"},
+			wantError: true,
+			wantText:  "no results found",
+			wantLimit: 8,
+		},
+		{
+			name:      "tavily error",
+			args:      map[string]any{"query": "golang"},
+			err:       &tavily.BadRequestError{APIError: tavily.APIError{StatusCode: 400, Message: "query is too long"}},
+			wantError: true,
+			wantText:  "rejected the search arguments",
+			wantLimit: 8,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockClient{resp: tt.resp, err: tt.err}
+			res, err := TavilyWebSearchHandler(client, tools)(context.Background(), callRequest(WebSearchToolName, tt.args))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.IsError != tt.wantError {
+				t.Fatalf("got error %v, want %v: %s", res.IsError, tt.wantError, callText(res))
+			}
+			if text := callText(res); !strings.Contains(text, tt.wantText) {
+				t.Errorf("got %q, want it to contain %q", text, tt.wantText)
+			}
+			if tt.json && res.StructuredContent == nil {
+				t.Error("json output has no structured content")
+			}
+			if tt.wantLimit != nil {
+				if got, _ := client.options.GetOption(tavily.OptionMaxResults); got != tt.wantLimit {
+					t.Errorf("got limit %v, want %v", got, tt.wantLimit)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchWithContentHandler(t *testing.T) {
+	raw := "# Go 1.24\n\nGo 1.24 adds generic type aliases.\n\nThe release also improves the map performance."
+	resp := &tavily.TavilySearchResponse{
+		Query:   "golang",
+		Results: []tavily.TavilySearchResult{{Title: "Go 1.24 released", URL: "https://go.dev/blog/go1.24", Content: "The Go team released Go 1.24.", RawContent: &raw}},
+	}
+	tools := newTools(t, nil)
+
+	tests := []struct {
+		name      string
+		args      map[string]any
+		resp      *tavily.TavilySearchResponse
+		err       error
+		wantError bool
+		wantText  string
+	}{
+		{
+			name:     "raw content",
+			args:     map[string]any{"query": "go generic aliases"},
+			resp:     resp,
+			wantText: "[Source 1] 《Go 1.24 released》\nURL: https://go.dev/blog/go1.24\n",
+		},
+		{
+			name:     "snippet without raw content",
+			args:     map[string]any{"query": "golang"},
+			resp:     &tavily.TavilySearchResponse{Results: []tavily.TavilySearchResult{{Title: "Go", URL: "https://go.dev", Content: "Go is an open source programming language."}}},
+			wantText: "Go is an open source programming language.",
+		},
+		{
+			name:      "max_tokens too small",
+			args:      map[string]any{"query": "golang", "max_tokens": 10},
+			wantError: true,
+			wantText:  "max_tokens error",
+		},
+		{
+			name:      "max_chars too small",
+			args:      map[string]any{"query": "golang", "max_chars": 100},
+			wantError: true,
+			wantText:  "max_chars error",
+		},
+		{
+			name:      "no pages",
+			args:      map[string]any{"query": "golang"},
+			resp:      &tavily.TavilySearchResponse{Query: "golang"},
+			wantError: true,
+			wantText:  "no pages found",
+		},
+		{
+			name:      "tavily error",
+			args:      map[string]any{"query": "golang"},
+			err:       &tavily.NetworkError{Err: errors.New("connection refused")},
+			wantError: true,
+			wantText:  "failed to reach tavily",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockClient{resp: tt.resp, err: tt.err}
+			res, err := TavilySearchWithContentHandler(client, tools)(context.Background(), callRequest(SearchContentToolName, tt.args))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.IsError != tt.wantError {
+				t.Fatalf("got error %v, want %v: %s", res.IsError, tt.wantError, callText(res))
+			}
+			if text := callText(res); !strings.Contains(text, tt.wantText) {
+				t.Errorf("got %q, want it to contain %q", text, tt.wantText)
+			}
+			if client.options != nil {
+				if got, _ := client.options.GetOption(tavily.OptionRawContent); got != true {
+					t.Errorf("got raw content %v, want true", got)
+				}
+			}
+		})
+	}
+}
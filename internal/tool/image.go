@@ -0,0 +1,309 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// ImageDownloadConcurrency is the max number of images downloading at the same time for one tool call
+	ImageDownloadConcurrency = 4
+	// ImageDownloadTimeout is the timeout of downloading one image
+	ImageDownloadTimeout = 15 * time.Second
+
+	ImageFormatOriginal = ""
+	ImageFormatJPEG     = "jpeg"
+	ImageFormatPNG      = "png"
+	ImageFormatWebP     = "webp"
+
+	DefaultImageMaxBytes    = 5 << 20
+	DefaultImageMaxPixels   = 1024 * 1024
+	DefaultImageBudgetBytes = 1 << 20
+	DefaultImageQuality     = 85
+
+	// minImageQuality is the lowest jpeg quality tried to fit the byte budget, before downscaling further
+	minImageQuality = 40
+	// maxImageShrinks is the max times the image is downscaled to fit the byte budget
+	maxImageShrinks = 4
+	// decodePixelsFactor is how many times the pixel budget an image may declare to be decoded and downscaled,
+	// larger images are rejected before decoding, a small file can declare a huge image
+	decodePixelsFactor = 8
+	// maxDecodePixels is the max pixels of an image decoded when there is no pixel budget, about 256MB decoded
+	maxDecodePixels = 64 << 20
+)
+
+// ImageOptions is the safeguards of the downloaded images
+type ImageOptions struct {
+	// MaxBytes is the max size of the downloaded image, larger images are rejected
+	MaxBytes int64
+	// MaxPixels is the pixel budget, larger images are downscaled, 0 means no limit
+	MaxPixels int
+	// BudgetBytes is the byte budget of the returned image, larger images are re-encoded and downscaled, 0 means no limit
+	BudgetBytes int
+	// Format is the format images are re-encoded to, jpeg, png or webp, empty keeps the original format when possible
+	Format string
+	// Quality is the jpeg quality
+	Quality int
+}
+
+// DefaultImageOptions
+func DefaultImageOptions() ImageOptions {
+	return ImageOptions{
+		MaxBytes:    DefaultImageMaxBytes,
+		MaxPixels:   DefaultImageMaxPixels,
+		BudgetBytes: DefaultImageBudgetBytes,
+		Format:      ImageFormatOriginal,
+		Quality:     DefaultImageQuality,
+	}
+}
+
+// normalize check the format and fill the unset max bytes and quality with the defaults
+func (opts ImageOptions) normalize() (ImageOptions, error) {
+	switch opts.Format {
+	case ImageFormatOriginal, ImageFormatJPEG, ImageFormatPNG, ImageFormatWebP:
+	default:
+		return opts, fmt.Errorf("image format %s is not supported, use %s, %s or %s", opts.Format, ImageFormatJPEG, ImageFormatPNG, ImageFormatWebP)
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultImageMaxBytes
+	}
+	if opts.Quality <= 0 || opts.Quality > 100 {
+		opts.Quality = DefaultImageQuality
+	}
+	return opts, nil
+}
+
+type imageDownload struct {
+	content *mcp.ImageContent
+	err     error
+}
+
+// downloadImages download the images by a bounded worker pool, the downloads keep the order of the images
+func (t *Tools) downloadImages(ctx context.Context, images []tavily.TavilySearchImage) []imageDownload {
+	downloads := make([]imageDownload, len(images))
+	jobs := make(chan int)
+
+	wg := &sync.WaitGroup{}
+	for w := 0; w < min(ImageDownloadConcurrency, len(images)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				downloadCtx, cancel := context.WithTimeout(ctx, ImageDownloadTimeout)
+				content, err := t.downloadImage(downloadCtx, images[i].URL)
+				cancel()
+				downloads[i] = imageDownload{content: content, err: err}
+			}
+		}()
+	}
+
+	for i := range images {
+		if ctx.Err() != nil {
+			downloads[i].err = ctx.Err()
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return downloads
+}
+
+func (t *Tools) downloadImage(ctx context.Context, url string) (content *mcp.ImageContent, err error) {
+	opts := t.image
+	start := time.Now()
+	var size int
+	ctx, span := startSpan(ctx, "download image", attribute.String("url.full", url))
+	defer func() {
+		observeImageDownload(ctx, err, time.Since(start), size)
+		span.SetAttributes(attribute.Int("image.bytes", size))
+		endSpan(span, err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("download image error: %v", err)
+	}
+	if err := t.guard.checkURL(req.URL); err != nil {
+		return nil, fmt.Errorf("download image error: %v", err)
+	}
+	req.Header.Set("Accept", "image/*")
+	slog.DebugContext(ctx, "downloading image", "url", url)
+	img, err := t.imageClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download image error: %v", err)
+	}
+	defer img.Body.Close()
+
+	if img.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download image error: status %d", img.StatusCode)
+	}
+	if img.ContentLength > opts.MaxBytes {
+		return nil, fmt.Errorf("download image error: image size %d exceeds the limit %d bytes", img.ContentLength, opts.MaxBytes)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(img.Body, opts.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("download image error: %v", err)
+	}
+	size = len(data)
+	if int64(len(data)) > opts.MaxBytes {
+		return nil, fmt.Errorf("download image error: image exceeds the limit %d bytes", opts.MaxBytes)
+	}
+
+	// trust the content, not the Content-Type header
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil, fmt.Errorf("download image error: content is %s, not an image", mimeType)
+	}
+
+	data, mimeType, err = fitImage(data, mimeType, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mcp.ImageContent{
+		Type:     "image",
+		MIMEType: mimeType,
+		Data:     base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// fitImage downscale and re-encode the image to fit the pixel and byte budget of the options
+func fitImage(data []byte, mimeType string, opts ImageOptions) ([]byte, string, error) {
+	withinBytes := opts.BudgetBytes <= 0 || len(data) <= opts.BudgetBytes
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) && withinBytes && opts.Format == ImageFormatOriginal {
+		// the formats without a decoder, like avif, bmp and ico, are returned as they are when they fit the byte budget
+		return data, mimeType, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("decode image error: %v", err)
+	}
+
+	format := targetFormat(mimeType, opts.Format)
+	pixels := int64(config.Width) * int64(config.Height)
+	decodeLimit := int64(maxDecodePixels)
+	if opts.MaxPixels > 0 {
+		decodeLimit = int64(opts.MaxPixels) * decodePixelsFactor
+	}
+	if pixels > decodeLimit {
+		return nil, "", fmt.Errorf("decode image error: image of %dx%d pixels exceeds the limit of %d pixels", config.Width, config.Height, decodeLimit)
+	}
+	withinPixels := opts.MaxPixels <= 0 || pixels <= int64(opts.MaxPixels)
+	if withinPixels && withinBytes && (opts.Format == ImageFormatOriginal || "image/"+format == mimeType) {
+		return data, mimeType, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("decode image error: %v", err)
+	}
+
+	scale := 1.0
+	if !withinPixels {
+		scale = math.Sqrt(float64(opts.MaxPixels) / float64(pixels))
+	}
+
+	quality := opts.Quality
+	for shrinks := 0; ; {
+		dst := resizeImage(src, scale)
+		encoded, err := encodeImage(dst, format, quality)
+		if err != nil {
+			return nil, "", err
+		}
+		if opts.BudgetBytes <= 0 || len(encoded) <= opts.BudgetBytes {
+			return encoded, "image/" + format, nil
+		}
+
+		// lower the jpeg quality first, then downscale
+		if format == ImageFormatJPEG && quality > minImageQuality {
+			quality = max(quality-15, minImageQuality)
+			continue
+		}
+		if shrinks >= maxImageShrinks {
+			return nil, "", fmt.Errorf("encode image error: image can not fit the budget %d bytes", opts.BudgetBytes)
+		}
+		shrinks++
+		scale *= 0.75
+	}
+}
+
+// targetFormat return the format the image is encoded to, images in other formats become jpeg
+func targetFormat(mimeType string, format string) string {
+	if format != ImageFormatOriginal {
+		return format
+	}
+	switch mimeType {
+	case "image/png", "image/gif":
+		return ImageFormatPNG
+	case "image/webp":
+		return ImageFormatWebP
+	default:
+		return ImageFormatJPEG
+	}
+}
+
+func resizeImage(src image.Image, scale float64) image.Image {
+	if scale >= 1 {
+		return src
+	}
+	bounds := src.Bounds()
+	width := max(1, int(float64(bounds.Dx())*scale))
+	height := max(1, int(float64(bounds.Dy())*scale))
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	return dst
+}
+
+func encodeImage(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case ImageFormatPNG:
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	case ImageFormatWebP:
+		err = nativewebp.Encode(&buf, img, nil)
+	default:
+		err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encode image error: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// flatten draw the image on a white background, jpeg has no alpha channel
+func flatten(img image.Image) image.Image {
+	if _, ok := img.(*image.YCbCr); ok {
+		return img
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,80 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// declarePNG rewrite the size declared by the IHDR chunk of the png, the pixel data is left as it is
+func declarePNG(data []byte, width, height uint32) []byte {
+	data = bytes.Clone(data)
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestFitImage(t *testing.T) {
+	small := encodePNG(t, 16, 16)
+	large := encodePNG(t, 200, 200)
+	bmp := append([]byte("BM"), make([]byte, 64)...)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		mimeType   string
+		opts       ImageOptions
+		wantErr    string
+		wantMIME   string
+		wantPixels int
+	}{
+		{name: "within budget", data: small, mimeType: "image/png", opts: DefaultImageOptions(), wantMIME: "image/png", wantPixels: 16 * 16},
+		{name: "downscaled", data: large, mimeType: "image/png", opts: ImageOptions{MaxPixels: 100 * 100, Quality: DefaultImageQuality}, wantMIME: "image/png", wantPixels: 100 * 100},
+		{name: "re-encoded", data: small, mimeType: "image/png", opts: ImageOptions{Format: ImageFormatJPEG, Quality: DefaultImageQuality}, wantMIME: "image/jpeg", wantPixels: 16 * 16},
+		{name: "declared too large", data: declarePNG(small, 30000, 30000), mimeType: "image/png", opts: DefaultImageOptions(), wantErr: "exceeds the limit"},
+		{name: "unknown format passed through", data: bmp, mimeType: "image/bmp", opts: DefaultImageOptions(), wantMIME: "image/bmp"},
+		{name: "unknown format re-encoded", data: bmp, mimeType: "image/bmp", opts: ImageOptions{Format: ImageFormatPNG}, wantErr: "unknown format"},
+		{name: "unknown format over budget", data: bmp, mimeType: "image/bmp", opts: ImageOptions{BudgetBytes: 10}, wantErr: "unknown format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, mimeType, err := fitImage(tt.data, tt.mimeType, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mimeType != tt.wantMIME {
+				t.Errorf("got mime type %s, want %s", mimeType, tt.wantMIME)
+			}
+			if tt.wantPixels == 0 {
+				return
+			}
+			config, _, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pixels := config.Width * config.Height; pixels > tt.wantPixels {
+				t.Errorf("got %d pixels, want at most %d", pixels, tt.wantPixels)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package tool
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/y7ut/mcp-tavily-search/pkg/param"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	CallStatusOK    = "ok"
+	CallStatusError = "error"
+)
+
+// callSummary is the facts of a tool call only the handler knows, it is filled by the handler
+// and reported by LoggingMiddleware and MetricsMiddleware
+type callSummary struct {
+	results int
+	// defaults is the default arguments of the tool, recorded when the call reaches the handler
+	defaults Defaults
+}
+
+type callSummaryKey struct{}
+
+// withCallSummary return the summary of the tool call in ctx, a new one is attached if not found
+func withCallSummary(ctx context.Context) (context.Context, *callSummary) {
+	if summary, ok := ctx.Value(callSummaryKey{}).(*callSummary); ok {
+		return ctx, summary
+	}
+	summary := &callSummary{}
+	return context.WithValue(ctx, callSummaryKey{}, summary), summary
+}
+
+// recordResults record the number of results the tool call returned
+func recordResults(ctx context.Context, n int) {
+	if summary, ok := ctx.Value(callSummaryKey{}).(*callSummary); ok {
+		summary.results = n
+	}
+}
+
+// LoggingMiddleware log one summary line of each tool call, with the query, latency, result count and status,
+// failed calls are logged at warn level
+func LoggingMiddleware(logger *slog.Logger) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			ctx, summary := withCallSummary(ctx)
+			start := time.Now()
+			result, err := next(ctx, request)
+
+			attrs := []slog.Attr{
+				slog.String("tool", request.Params.Name),
+				slog.String("client", ClientFromContext(ctx)),
+				slog.String("query", callQuery(request)),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int("results", summary.results),
+			}
+			if addr := ClientAddrFromContext(ctx); addr != "" {
+				attrs = append(attrs, slog.String("client_addr", addr))
+			}
+			level, status := slog.LevelInfo, callStatus(result, err)
+			switch {
+			case err != nil:
+				level = slog.LevelWarn
+				attrs = append(attrs, slog.String("error", err.Error()))
+			case status == CallStatusError:
+				level = slog.LevelWarn
+				attrs = append(attrs, slog.String("error", resultText(result)))
+			}
+			attrs = append(attrs, slog.String("status", status))
+			if hit, ok := cacheHit(result); ok {
+				attrs = append(attrs, slog.Bool("cache_hit", hit))
+			}
+			if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+				attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+			}
+			logger.LogAttrs(ctx, level, "tool call", attrs...)
+
+			return result, err
+		}
+	}
+}
+
+// callQuery return what the tool call searched for, the keyword, the question or the urls
+func callQuery(request mcp.CallToolRequest) string {
+	args := request.GetArguments()
+	for _, key := range []string{"keyword", "question", "query"} {
+		var query string
+		if err := param.Assign(&query, args[key]); err == nil && query != "" {
+			return query
+		}
+	}
+	var urls []string
+	if err := param.Assign(&urls, args["urls"]); err == nil {
+		return strings.Join(urls, ",")
+	}
+	return ""
+}
+
+// callStatus return the status of the tool call by its result
+func callStatus(result *mcp.CallToolResult, err error) string {
+	if err != nil || (result != nil && result.IsError) {
+		return CallStatusError
+	}
+	return CallStatusOK
+}
+
+// cacheHit return the cache_hit of the result _meta, false if the result does not report it
+func cacheHit(result *mcp.CallToolResult) (hit bool, ok bool) {
+	if result == nil || result.Meta == nil {
+		return false, false
+	}
+	hit, ok = result.Meta.AdditionalFields["cache_hit"].(bool)
+	return hit, ok
+}
+
+// resultText return the text of the first text content of the result
+func resultText(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
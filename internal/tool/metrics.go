@@ -0,0 +1,79 @@
+package tool
+
+import (
+	"context"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/y7ut/mcp-tavily-search/pkg/param"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+// Metrics record the tool calls and the image downloads, implemented by internal/metrics
+type Metrics interface {
+	// ObserveToolCall is called for each finished tool call, topic and depth are empty if the tool has no such argument
+	ObserveToolCall(tool, topic, depth, status string, latency time.Duration, results int)
+	// ObserveImageDownload is called for each image download, bytes is the size of the downloaded image
+	ObserveImageDownload(status string, latency time.Duration, bytes int)
+}
+
+type metricsKey struct{}
+
+// MetricsMiddleware record each tool call to the metrics, the image downloads of the call are recorded as well
+func MetricsMiddleware(metrics Metrics) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			ctx, summary := withCallSummary(ctx)
+			start := time.Now()
+			result, err := next(context.WithValue(ctx, metricsKey{}, metrics), request)
+
+			topic, depth := callLabels(request, summary.defaults)
+			metrics.ObserveToolCall(request.Params.Name, topic, depth, callStatus(result, err), time.Since(start), summary.results)
+			return result, err
+		}
+	}
+}
+
+// callLabels return the topic and depth of the tool call, the omitted arguments are taken from the defaults of the tool.
+// unknown values are reported as "other" so the arguments of the model can not blow up the label values
+func callLabels(request mcp.CallToolRequest, defaults Defaults) (topic, depth string) {
+	label := func(key string, values ...string) string {
+		v := request.GetArguments()[key]
+		if v == nil {
+			v = defaults.get(key)
+		}
+		if v == nil {
+			return ""
+		}
+		var s string
+		if err := param.Assign(&s, v); err == nil {
+			for _, value := range values {
+				if s == value {
+					return s
+				}
+			}
+		}
+		return "other"
+	}
+
+	topic = label("topic", tavily.TopicGeneral, tavily.TopicNews)
+	depth = label("search_depth", tavily.DepthBasic, tavily.DepthAdvanced)
+	if depth == "" {
+		depth = label("extract_depth", tavily.DepthBasic, tavily.DepthAdvanced)
+	}
+	return topic, depth
+}
+
+// observeImageDownload record the image download to the metrics of the tool call in ctx
+func observeImageDownload(ctx context.Context, err error, latency time.Duration, bytes int) {
+	metrics, ok := ctx.Value(metricsKey{}).(Metrics)
+	if !ok {
+		return
+	}
+	status := CallStatusOK
+	if err != nil {
+		status = CallStatusError
+	}
+	metrics.ObserveImageDownload(status, latency, bytes)
+}
@@ -0,0 +1,81 @@
+package tool
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Options is the settings of the tools of one server
+type Options struct {
+	// Defaults override the default arguments of the tools, unset fields keep the builtin defaults
+	Defaults map[string]Defaults
+	// Image is the safeguards of the downloaded images
+	Image ImageOptions
+	// URLPolicy is the rules of the urls images are fetched from
+	URLPolicy URLPolicy
+}
+
+// DefaultOptions
+func DefaultOptions() Options {
+	return Options{
+		Image:     DefaultImageOptions(),
+		URLPolicy: DefaultURLPolicy(),
+	}
+}
+
+// Tools is the settings the tool handlers of one server read, each server has its own
+type Tools struct {
+	defaults    map[string]Defaults
+	image       ImageOptions
+	guard       *urlGuard
+	imageClient *http.Client
+}
+
+// NewTools check the options and build the settings of the tools
+func NewTools(opts Options) (*Tools, error) {
+	defaults, err := mergeDefaults(opts.Defaults)
+	if err != nil {
+		return nil, err
+	}
+	image, err := opts.Image.normalize()
+	if err != nil {
+		return nil, err
+	}
+	guard, err := newURLGuard(opts.URLPolicy)
+	if err != nil {
+		return nil, err
+	}
+	return &Tools{
+		defaults:    defaults,
+		image:       image,
+		guard:       guard,
+		imageClient: guard.client(ImageDownloadTimeout),
+	}, nil
+}
+
+// Defaults return the default arguments of the tool
+func (t *Tools) Defaults(name string) Defaults {
+	return t.defaults[name]
+}
+
+// argument return the argument of the call, or the default of the tool when it is omitted
+func (t *Tools) argument(request mcp.CallToolRequest, key string) any {
+	if v, ok := request.GetArguments()[key]; ok && v != nil {
+		return v
+	}
+	return t.Defaults(request.Params.Name).get(key)
+}
+
+// withDefaults record the default arguments of the tool in the call summary, so the middlewares label the call
+// by the arguments the handler actually used
+func (t *Tools) withDefaults(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if summary, ok := ctx.Value(callSummaryKey{}).(*callSummary); ok {
+			summary.defaults = t.Defaults(request.Params.Name)
+		}
+		return next(ctx, request)
+	}
+}
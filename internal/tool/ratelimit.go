@@ -0,0 +1,257 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultQueueTimeout is the max time a tool call waits for the rate limits before it is rejected
+	DefaultQueueTimeout = 10 * time.Second
+
+	// clientIdleTimeout is the idle time after which the limits of a client are dropped
+	clientIdleTimeout = 10 * time.Minute
+)
+
+// RateLimit is the limits of the tool calls, zero values mean no limit
+type RateLimit struct {
+	// Rate is the tool calls allowed per second, refilling the token bucket
+	Rate float64
+	// Burst is the size of the token bucket, default is the rate rounded up
+	Burst int
+	// MaxInFlight is the max tool calls running at the same time
+	MaxInFlight int
+}
+
+// RateLimitOptions is the limits of all the tool calls of the server and of each client
+type RateLimitOptions struct {
+	Global    RateLimit
+	PerClient RateLimit
+	// QueueTimeout is the max time a call waits for the limits, then it is rejected
+	QueueTimeout time.Duration
+}
+
+// Enabled return true if any limit is set
+func (o RateLimitOptions) Enabled() bool {
+	return o.Global != (RateLimit{}) || o.PerClient != (RateLimit{})
+}
+
+// Validate check the limits are not negative
+func (o RateLimitOptions) Validate() error {
+	for _, l := range []RateLimit{o.Global, o.PerClient} {
+		if l.Rate < 0 || l.Burst < 0 || l.MaxInFlight < 0 {
+			return fmt.Errorf("rate limit error: rate, burst and max in-flight must not be negative")
+		}
+	}
+	if o.QueueTimeout < 0 {
+		return fmt.Errorf("rate limit error: queue timeout must not be negative")
+	}
+	return nil
+}
+
+// limiter is the token bucket and the in-flight slots of a RateLimit, nil fields mean no limit
+type limiter struct {
+	bucket   *rate.Limiter
+	slots    chan struct{}
+	lastUsed time.Time
+	inFlight int
+}
+
+func newLimiter(l RateLimit) *limiter {
+	lim := &limiter{}
+	if l.Rate > 0 {
+		burst := l.Burst
+		if burst <= 0 {
+			burst = int(math.Ceil(l.Rate))
+		}
+		lim.bucket = rate.NewLimiter(rate.Limit(l.Rate), burst)
+	}
+	if l.MaxInFlight > 0 {
+		lim.slots = make(chan struct{}, l.MaxInFlight)
+	}
+	return lim
+}
+
+// rateLimiter hold the global limiter and the limiters of each client
+type rateLimiter struct {
+	opts   RateLimitOptions
+	global *limiter
+
+	mu        sync.Mutex
+	clients   map[string]*limiter
+	lastSweep time.Time
+}
+
+// RateLimitError means the tool call is rejected by the rate limits
+type RateLimitError struct {
+	// Reason is which limit rejected the call
+	Reason string
+	// RetryAfter is when the call is likely to be accepted
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited, %s, retry after %d s", e.Reason, retryAfterSeconds(e.RetryAfter))
+}
+
+// RateLimitMiddleware limit the rate and the in-flight tool calls of the server and of each client,
+// calls over the limits wait up to the queue timeout, then they are rejected with a rate limited tool error
+func RateLimitMiddleware(opts RateLimitOptions) server.ToolHandlerMiddleware {
+	if opts.QueueTimeout <= 0 {
+		opts.QueueTimeout = DefaultQueueTimeout
+	}
+	rl := &rateLimiter{
+		opts:    opts,
+		global:  newLimiter(opts.Global),
+		clients: map[string]*limiter{},
+	}
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			release, err := rl.wait(ctx, ClientFromContext(ctx))
+			if err != nil {
+				if limitErr, ok := err.(*RateLimitError); ok {
+					return rateLimitResult(limitErr), nil
+				}
+				return toolError(err), nil
+			}
+			defer release()
+			return next(ctx, request)
+		}
+	}
+}
+
+// client return the limiter of the client, the limiters idle for a while are dropped
+func (rl *rateLimiter) client(id string) *limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(rl.lastSweep) > clientIdleTimeout {
+		for key, lim := range rl.clients {
+			if lim.inFlight == 0 && now.Sub(lim.lastUsed) > clientIdleTimeout {
+				delete(rl.clients, key)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	lim, ok := rl.clients[id]
+	if !ok {
+		lim = newLimiter(rl.opts.PerClient)
+		rl.clients[id] = lim
+	}
+	lim.lastUsed = now
+	lim.inFlight++
+	return lim
+}
+
+// done mark the call of the client finished
+func (rl *rateLimiter) done(lim *limiter) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	lim.inFlight--
+	lim.lastUsed = time.Now()
+}
+
+// wait wait for the tokens and the in-flight slots of the client and the server until the queue timeout,
+// release must be called when the call is finished
+func (rl *rateLimiter) wait(ctx context.Context, clientID string) (release func(), err error) {
+	client := rl.client(clientID)
+	deadline := time.Now().Add(rl.opts.QueueTimeout)
+
+	// tokens: reserve both buckets, wait for the later one if it is before the deadline
+	var delay time.Duration
+	var reservations []*rate.Reservation
+	cancel := func() {
+		for _, r := range reservations {
+			r.Cancel()
+		}
+	}
+	for _, lim := range []struct {
+		scope string
+		*limiter
+	}{{"client", client}, {"server", rl.global}} {
+		if lim.bucket == nil {
+			continue
+		}
+		r := lim.bucket.Reserve()
+		reservations = append(reservations, r)
+		if !r.OK() || time.Now().Add(r.Delay()).After(deadline) {
+			retryAfter := r.Delay()
+			cancel()
+			rl.done(client)
+			if !r.OK() {
+				retryAfter = rl.opts.QueueTimeout
+			}
+			return nil, &RateLimitError{Reason: fmt.Sprintf("too many tool calls of the %s", lim.scope), RetryAfter: retryAfter}
+		}
+		delay = max(delay, r.Delay())
+	}
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			cancel()
+			rl.done(client)
+			return nil, ctx.Err()
+		}
+	}
+
+	// in-flight slots: take the client slot then the server slot until the deadline
+	queueCtx, stop := context.WithDeadline(ctx, deadline)
+	defer stop()
+	var taken []chan struct{}
+	releaseSlots := func() {
+		for _, slots := range taken {
+			<-slots
+		}
+	}
+	for _, lim := range []struct {
+		scope string
+		*limiter
+	}{{"client", client}, {"server", rl.global}} {
+		if lim.slots == nil {
+			continue
+		}
+		select {
+		case lim.slots <- struct{}{}:
+			taken = append(taken, lim.slots)
+		case <-queueCtx.Done():
+			releaseSlots()
+			rl.done(client)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, &RateLimitError{Reason: fmt.Sprintf("too many tool calls of the %s in flight", lim.scope), RetryAfter: time.Second}
+		}
+	}
+
+	return func() {
+		releaseSlots()
+		rl.done(client)
+	}, nil
+}
+
+// rateLimitResult turn the rate limit error into a tool error, the retry after is also in the result _meta
+func rateLimitResult(err *RateLimitError) *mcp.CallToolResult {
+	result := mcp.NewToolResultError(err.Error() + ". Wait before calling the tools again.")
+	result.Meta = mcp.NewMetaFromMap(map[string]any{
+		"rate_limited":        true,
+		"retry_after_seconds": retryAfterSeconds(err.RetryAfter),
+	})
+	return result
+}
+
+// retryAfterSeconds round the duration up to whole seconds, at least 1
+func retryAfterSeconds(d time.Duration) int {
+	return max(int(math.Ceil(d.Seconds())), 1)
+}
@@ -1,128 +1,142 @@
 package tool
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"sync"
 
 	"github.com/mark3labs/mcp-go/mcp"
-	"github.com/y7ut/mcp-tavily-search/internal/tavily"
+	"github.com/mark3labs/mcp-go/server"
 	"github.com/y7ut/mcp-tavily-search/pkg/param"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
 )
 
 // TavilySearchHandler is the handler for the search tool
-func TavilySearchHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	var keyword string
-	if err := param.Assign(&keyword, request.Params.Arguments["keyword"]); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("keyword error: %v", err)), nil
-	}
-
-	result, err := tavily.Search(
-		ctx,
-		keyword,
-		tavily.WithOption("topic", request.Params.Arguments["topic"]),
-		tavily.WithOption("days", request.Params.Arguments["days"]),
-		tavily.WithOption("limit", request.Params.Arguments["limit"]),
-		tavily.WithOption("search_depth", request.Params.Arguments["search_depth"]),
-	)
-
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
+func TavilySearchHandler(client TavilyClient, tools *Tools) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var keyword string
+		if err := param.Assign(&keyword, request.GetArguments()["keyword"]); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("keyword error: %v", err)), nil
+		}
+		format, err := outputFormat(request)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("output_format error: %v", err)), nil
+		}
+		var rawContent bool
+		if v, ok := request.GetArguments()["include_raw_content"]; ok && v != nil {
+			if err := param.Assign(&rawContent, v); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("include_raw_content error: %v", err)), nil
+			}
+		}
 
-	if len(result) == 0 {
-		return mcp.NewToolResultError(fmt.Sprintf("no news found for keyword: %s", keyword)), nil
-	}
+		result, err := client.Search(
+			ctx,
+			keyword,
+			tavily.WithOption("topic", tools.argument(request, "topic")),
+			tavily.WithOption("days", tools.argument(request, "days")),
+			tavily.WithOption("limit", tools.argument(request, "limit")),
+			tavily.WithOption("search_depth", tools.argument(request, "search_depth")),
+			tavily.WithOption("include_domains", request.GetArguments()["include_domains"]),
+			tavily.WithOption("exclude_domains", request.GetArguments()["exclude_domains"]),
+			// raw content is only returned by the json output
+			tavily.WithRawContent(rawContent && format == OutputJSON),
+		)
+
+		if err != nil {
+			return toolError(err), nil
+		}
 
-	textContents := make([]mcp.Content, len(result))
-	for i, news := range result {
-		textContents[i] = mcp.TextContent{
-			Type: "text",
-			Text: fmt.Sprintf("《%s》: %s\n %s", news.Title, news.URL, news.Content),
+		recordResults(ctx, len(result.Results))
+		if len(result.Results) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("no news found for keyword: %s", keyword)), nil
 		}
 
+		contents, structured := searchContents(result, format)
+		return &mcp.CallToolResult{
+			Result:            searchResult(result),
+			Content:           append(contents, downgradeNote(result)...),
+			StructuredContent: structured,
+		}, nil
 	}
-
-	return &mcp.CallToolResult{
-		Content: textContents,
-	}, nil
 }
 
 // TavilySearchImageHandler is the handler for the search image tool, return image content
-func TavilySearchImageHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	var keyword string
-	if err := param.Assign(&keyword, request.Params.Arguments["keyword"]); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("keyword error: %v", err)), nil
-	}
+func TavilySearchImageHandler(client TavilyClient, tools *Tools) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var keyword string
+		if err := param.Assign(&keyword, request.GetArguments()["keyword"]); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("keyword error: %v", err)), nil
+		}
 
-	result, err := tavily.SearchImage(
-		ctx,
-		keyword,
-		tavily.WithOption("topic", request.Params.Arguments["topic"]),
-		tavily.WithOption("days", request.Params.Arguments["days"]),
-		tavily.WithOption("limit", request.Params.Arguments["limit"]),
-		tavily.WithOption("search_depth", request.Params.Arguments["search_depth"]),
-	)
-
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
+		result, err := client.SearchImage(
+			ctx,
+			keyword,
+			tavily.WithOption("topic", tools.argument(request, "topic")),
+			tavily.WithOption("days", tools.argument(request, "days")),
+			tavily.WithOption("limit", tools.argument(request, "limit")),
+			tavily.WithOption("search_depth", tools.argument(request, "search_depth")),
+			tavily.WithOption("include_domains", request.GetArguments()["include_domains"]),
+			tavily.WithOption("exclude_domains", request.GetArguments()["exclude_domains"]),
+		)
+
+		if err != nil {
+			return toolError(err), nil
+		}
 
-	if len(result) == 0 {
-		return mcp.NewToolResultError(fmt.Sprintf("no news found for keyword: %s", keyword)), nil
-	}
+		if len(result.Images) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("no news found for keyword: %s", keyword)), nil
+		}
 
-	imgContents := make([]mcp.Content, 0)
-	wg := &sync.WaitGroup{}
-	for _, news := range result {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			content, err := downloadImage(news.URL)
-			if err != nil {
-				fmt.Fprint(os.Stderr, err)
-				return
+		images := result.Images
+		downloads := tools.downloadImages(ctx, images)
+
+		imgContents := make([]mcp.Content, 0, len(downloads)*2)
+		failed := 0
+		for i, download := range downloads {
+			if download.err != nil {
+				failed++
+				imgContents = append(imgContents, mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("failed to download image %d (%s): %v\n %s", i+1, images[i].URL, download.err, images[i].Description),
+				})
+				continue
 			}
-			imgContents = append(imgContents, content)
-			imgContents = append(imgContents, mcp.TextContent{
+			imgContents = append(imgContents, download.content, mcp.TextContent{
 				Type: "text",
-				Text: news.Description,
+				Text: images[i].Description,
 			})
-		}()
-	}
-
-	wg.Wait()
+		}
 
-	return &mcp.CallToolResult{
-		Content: imgContents,
-	}, nil
+		recordResults(ctx, len(downloads)-failed)
+		return &mcp.CallToolResult{
+			Result:  searchResult(result),
+			Content: append(imgContents, downgradeNote(result)...),
+			IsError: failed == len(downloads),
+		}, nil
+	}
 }
 
-func downloadImage(url string) (*mcp.ImageContent, error) {
-	imgBuffer := bytes.NewBuffer([]byte{})
-	imgBase64Buffer := base64.NewEncoder(base64.StdEncoding, imgBuffer)
-	defer imgBase64Buffer.Close()
-	img, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("download image error: %v", err)
+// searchResult tell the client whether the search response is served from cache, shared with an identical search in flight,
+// and whether it is downgraded to basic by the credit budget, in the result _meta
+func searchResult(result *tavily.TavilySearchResponse) mcp.Result {
+	meta := map[string]any{"cache_hit": result.CacheHit}
+	if result.Shared {
+		meta["shared"] = true
 	}
-	mimeType := img.Header.Get("Content-Type")
-	defer img.Body.Close()
-
-	_, err = io.Copy(imgBase64Buffer, img.Body)
-	if err != nil {
-		return nil, fmt.Errorf("encode image error: %v", err)
+	if result.Downgraded {
+		meta["budget_downgraded"] = true
 	}
-	fmt.Fprintf(os.Stderr, "Downloading image: %s\n", url)
-	return &mcp.ImageContent{
-		Type:     "image",
-		MIMEType: mimeType,
-		Data:     imgBuffer.String(),
-	}, nil
+	return mcp.Result{
+		Meta: mcp.NewMetaFromMap(meta),
+	}
+}
 
+// downgradeNote tell the model the search is downgraded to basic by the credit budget, nil if it is not
+func downgradeNote(result *tavily.TavilySearchResponse) []mcp.Content {
+	if !result.Downgraded {
+		return nil
+	}
+	return []mcp.Content{mcp.TextContent{
+		Type: "text",
+		Text: "note: the advanced search is downgraded to basic, the credit budget of the server is nearly exhausted.",
+	}}
 }
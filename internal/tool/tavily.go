@@ -0,0 +1,17 @@
+package tool
+
+import (
+	"context"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+// TavilyClient is the tavily api the tools call, implemented by *tavily.Client,
+// a mock of it is enough to test the handlers without the tavily api
+type TavilyClient interface {
+	Search(ctx context.Context, query string, h ...tavily.WithOptionHelper) (*tavily.TavilySearchResponse, error)
+	SearchImage(ctx context.Context, query string, h ...tavily.WithOptionHelper) (*tavily.TavilySearchResponse, error)
+	SearchAnswer(ctx context.Context, query string, mode tavily.AnswerMode, h ...tavily.WithOptionHelper) (*tavily.TavilySearchResponse, error)
+	Extract(ctx context.Context, urls []string, h ...tavily.WithOptionHelper) (*tavily.TavilyExtractResponse, error)
+	KeyUsage() []tavily.KeyUsage
+}
@@ -0,0 +1,97 @@
+package tool
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of the tools
+const tracerName = "github.com/y7ut/mcp-tavily-search/internal/tool"
+
+// propagator read the w3c trace context and baggage from the _meta of the tool call
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// TracingMiddleware start a span for each tool call, the tavily requests and image downloads of the call are its children.
+// when the _meta of the request carries a traceparent, the span continues that trace
+func TracingMiddleware(tp trace.TracerProvider) server.ToolHandlerMiddleware {
+	tracer := tp.Tracer(tracerName)
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			ctx = propagator.Extract(ctx, metaCarrier(request.Params.Meta))
+			ctx, span := tracer.Start(ctx, "tool "+request.Params.Name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("mcp.tool", request.Params.Name),
+					attribute.String("mcp.client", ClientFromContext(ctx)),
+					attribute.String("tool.query", callQuery(request)),
+				),
+			)
+			defer span.End()
+			if addr := ClientAddrFromContext(ctx); addr != "" {
+				span.SetAttributes(attribute.String("client.address", addr))
+			}
+
+			ctx, summary := withCallSummary(ctx)
+			result, err := next(ctx, request)
+
+			// the defaults of the tool are known once the call reached the handler
+			topic, depth := callLabels(request, summary.defaults)
+			status := callStatus(result, err)
+			span.SetAttributes(
+				attribute.String("tool.topic", topic),
+				attribute.String("tool.depth", depth),
+				attribute.Int("tool.results", summary.results),
+				attribute.String("tool.status", status),
+			)
+			if hit, ok := cacheHit(result); ok {
+				span.SetAttributes(attribute.Bool("tool.cache_hit", hit))
+			}
+			switch {
+			case err != nil:
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			case status == CallStatusError:
+				span.SetStatus(codes.Error, resultText(result))
+			default:
+				span.SetStatus(codes.Ok, "")
+			}
+			return result, err
+		}
+	}
+}
+
+// metaCarrier return the string fields of the _meta as the carrier of the trace context
+func metaCarrier(meta *mcp.Meta) propagation.MapCarrier {
+	carrier := propagation.MapCarrier{}
+	if meta == nil {
+		return carrier
+	}
+	for k, v := range meta.AdditionalFields {
+		if s, ok := v.(string); ok {
+			carrier[k] = s
+		}
+	}
+	return carrier
+}
+
+// startSpan start a child span of the span in ctx, it is a noop when the tool call is not traced
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan set the status of the span by err, then end it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+}
@@ -0,0 +1,28 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// KeyUsageHandler is the handler for the key usage resource, return the usage counters of each api key
+func KeyUsageHandler(client TavilyClient) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		usage, err := json.MarshalIndent(client.KeyUsage(), "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("key usage marshal error: %v", err)
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/json",
+				Text:     string(usage),
+			},
+		}, nil
+	}
+}
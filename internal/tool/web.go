@@ -0,0 +1,87 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/y7ut/mcp-tavily-search/pkg/param"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+// TavilyWebSearchHandler is the handler for the web search tool, every field of the search request is an argument,
+// the answer and the images are returned with the results when they are requested
+func TavilyWebSearchHandler(client TavilyClient, tools *Tools) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var query string
+		if err := param.Assign(&query, request.GetArguments()["query"]); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("query error: %v", err)), nil
+		}
+		format, err := outputFormat(request)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("output_format error: %v", err)), nil
+		}
+
+		// max_results is the limit of the other tools, the default of the tool is its limit
+		maxResults := request.GetArguments()["max_results"]
+		if maxResults == nil {
+			maxResults = tools.Defaults(request.Params.Name).get("limit")
+		}
+
+		result, err := client.Search(
+			ctx,
+			query,
+			tavily.WithOption("limit", maxResults),
+			tavily.WithOption("topic", tools.argument(request, "topic")),
+			tavily.WithOption("days", tools.argument(request, "days")),
+			tavily.WithOption("search_depth", tools.argument(request, "search_depth")),
+			tavily.WithOption("include_answer", request.GetArguments()["include_answer"]),
+			tavily.WithOption("include_raw_content", request.GetArguments()["include_raw_content"]),
+			tavily.WithOption("include_images", request.GetArguments()["include_images"]),
+			tavily.WithOption("include_image_descriptions", request.GetArguments()["include_image_descriptions"]),
+			tavily.WithOption("include_domains", request.GetArguments()["include_domains"]),
+			tavily.WithOption("exclude_domains", request.GetArguments()["exclude_domains"]),
+		)
+
+		if err != nil {
+			return toolError(err), nil
+		}
+
+		recordResults(ctx, len(result.Results))
+		if len(result.Results) == 0 && len(result.Images) == 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("no results found for query: %s", query)), nil
+		}
+
+		contents, structured := searchContents(result, format)
+		if format != OutputJSON {
+			// the json output carries the answer and the images itself
+			if result.Answer != nil && strings.TrimSpace(*result.Answer) != "" {
+				contents = append([]mcp.Content{mcp.NewTextContent("Answer: " + strings.TrimSpace(*result.Answer))}, contents...)
+			}
+			if len(result.Images) > 0 {
+				contents = append(contents, mcp.NewTextContent(imageList(result.Images)))
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Result:            searchResult(result),
+			Content:           append(contents, downgradeNote(result)...),
+			StructuredContent: structured,
+		}, nil
+	}
+}
+
+// imageList list the urls of the images and their descriptions, the images are not downloaded
+func imageList(images []tavily.TavilySearchImage) string {
+	var text strings.Builder
+	text.WriteString("Images:")
+	for i, image := range images {
+		fmt.Fprintf(&text, "\n%d. %s", i+1, image.URL)
+		if description := strings.TrimSpace(image.Description); description != "" {
+			fmt.Fprintf(&text, "\n   %s", description)
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,110 @@
+// Package tracing builds the opentelemetry tracer provider of the server, exporting the spans over otlp.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+
+	// ServiceName is the service.name of the exported spans
+	ServiceName = "mcp-tavily-search"
+)
+
+// Options is the settings of the otlp exporter
+type Options struct {
+	// Endpoint is the host:port of the otlp collector, empty disables tracing
+	Endpoint string
+	// Protocol is grpc or http
+	Protocol string
+	// Insecure disables the tls of the exporter
+	Insecure bool
+	// Headers is the headers sent with each export, like the auth of the collector
+	Headers map[string]string
+	// SampleRatio is the ratio of the root spans sampled, spans with a sampled parent are always sampled
+	SampleRatio float64
+}
+
+// DefaultOptions
+func DefaultOptions() Options {
+	return Options{
+		Protocol:    ProtocolGRPC,
+		SampleRatio: 1,
+	}
+}
+
+// New create the tracer provider exporting over otlp, call shutdown to flush the spans before exiting.
+// a noop provider is returned when the endpoint is empty
+func New(ctx context.Context, version string, opts Options) (trace.TracerProvider, func(context.Context) error, error) {
+	if opts.Endpoint == "" {
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	}
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		return nil, nil, fmt.Errorf("trace sample ratio %v must between 0 and 1", opts.SampleRatio)
+	}
+
+	var client otlptrace.Client
+	switch opts.Protocol {
+	case ProtocolGRPC, "":
+		grpcOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint), otlptracegrpc.WithHeaders(opts.Headers)}
+		if opts.Insecure {
+			grpcOpts = append(grpcOpts, otlptracegrpc.WithInsecure())
+		}
+		client = otlptracegrpc.NewClient(grpcOpts...)
+	case ProtocolHTTP:
+		httpOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(opts.Endpoint), otlptracehttp.WithHeaders(opts.Headers)}
+		if opts.Insecure {
+			httpOpts = append(httpOpts, otlptracehttp.WithInsecure())
+		}
+		client = otlptracehttp.NewClient(httpOpts...)
+	default:
+		return nil, nil, fmt.Errorf("otlp protocol %s is not supported, use %s or %s", opts.Protocol, ProtocolGRPC, ProtocolHTTP)
+	}
+
+	exporter, err := otlptrace.New(ctx, client)
+	if err != nil {
+		return nil, nil, fmt.Errorf("otlp exporter error: %v", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, nil, fmt.Errorf("otlp resource error: %v", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	return tp, tp.Shutdown, nil
+}
+
+// ParseHeaders parse the key=value headers of the exporter
+func ParseHeaders(headers []string) (map[string]string, error) {
+	res := make(map[string]string, len(headers))
+	for _, header := range headers {
+		k, v, ok := strings.Cut(header, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("otlp header %q is not key=value", header)
+		}
+		res[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return res, nil
+}
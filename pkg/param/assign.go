@@ -56,6 +56,16 @@ func Assign(dest, src any) error {
 			return err
 		}
 		destElem.SetBool(boolValue)
+	case reflect.Slice:
+		if destElem.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported dest slice type: %s", destElem.Type())
+		}
+		// Convert src to []string
+		sliceValue, err := toStringSlice(src)
+		if err != nil {
+			return err
+		}
+		destElem.Set(reflect.ValueOf(sliceValue).Convert(destElem.Type()))
 	default:
 		return fmt.Errorf("unsupported dest type: %s", destElem.Kind())
 	}
@@ -85,6 +95,28 @@ func toString(src any) (string, error) {
 	}
 }
 
+// toStringSlice converts src to a []string, src can be a slice of string or a slice of any holding strings
+func toStringSlice(src any) ([]string, error) {
+	switch v := src.(type) {
+	case []string:
+		return v, nil
+	case []any:
+		res := make([]string, 0, len(v))
+		for _, item := range v {
+			str, err := toString(item)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, str)
+		}
+		return res, nil
+	case string:
+		return []string{v}, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %T to []string", v)
+	}
+}
+
 // toFloat64 converts src to a float64
 func toFloat64(src any) (float64, error) {
 	switch v := src.(type) {
@@ -0,0 +1,59 @@
+package tavily
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AnswerMode is the mode of the answer generated by tavily, empty means no answer
+type AnswerMode string
+
+const (
+	AnswerNone     AnswerMode = ""
+	AnswerBasic    AnswerMode = "basic"
+	AnswerAdvanced AnswerMode = "advanced"
+)
+
+// MarshalJSON encode the empty mode as false, tavily accepts a bool or the mode name
+func (m AnswerMode) MarshalJSON() ([]byte, error) {
+	if m == AnswerNone {
+		return []byte("false"), nil
+	}
+	return json.Marshal(string(m))
+}
+
+// UnmarshalJSON decode a bool or the mode name
+func (m *AnswerMode) UnmarshalJSON(b []byte) error {
+	var enabled bool
+	if err := json.Unmarshal(b, &enabled); err == nil {
+		*m = AnswerNone
+		if enabled {
+			*m = AnswerBasic
+		}
+		return nil
+	}
+	var mode string
+	if err := json.Unmarshal(b, &mode); err != nil {
+		return err
+	}
+	parsed, err := parseAnswerMode(mode)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// parseAnswerMode parse the include_answer option, true means basic
+func parseAnswerMode(v string) (AnswerMode, error) {
+	switch v {
+	case "", "false":
+		return AnswerNone, nil
+	case "true", string(AnswerBasic):
+		return AnswerBasic, nil
+	case string(AnswerAdvanced):
+		return AnswerAdvanced, nil
+	default:
+		return AnswerNone, fmt.Errorf("tavily answer mode error: %s is not a valid answer mode", v)
+	}
+}
@@ -0,0 +1,315 @@
+package tavily
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// BudgetRefuse refuse the calls exceeding the budget
+	BudgetRefuse = "refuse"
+	// BudgetDowngrade downgrade the advanced searches exceeding the budget to basic, refuse them if basic still exceeds it
+	BudgetDowngrade = "downgrade"
+
+	BudgetDaily   = "daily"
+	BudgetMonthly = "monthly"
+
+	// budgetSaveInterval is the least time between two writes of the budget file, the counters changed in between
+	// are written together
+	budgetSaveInterval = 5 * time.Second
+)
+
+// BudgetOptions is the credit caps of each api key, a cap of 0 means no cap
+type BudgetOptions struct {
+	// Daily is the max estimated credits a key spends per day, days start at 00:00 UTC
+	Daily int
+	// Monthly is the max estimated credits a key spends per month, months start on the 1st at 00:00 UTC
+	Monthly int
+	// Policy is refuse or downgrade
+	Policy string
+	// File persists the counters across restarts, empty keeps them in memory only
+	File string
+}
+
+// Budget cap the estimated credits spent by each api key per day and per month, it is safe for concurrent use.
+// credits are reserved by the estimate before a request is sent, then settled with the actual credits
+type Budget struct {
+	mu    sync.Mutex
+	opts  BudgetOptions
+	usage map[string]*budgetUsage
+	now   func() time.Time
+	// dirty is true when the counters changed since they were last written, saved is when they were
+	dirty bool
+	saved time.Time
+
+	// fileMu serialize the writes of the file, it is taken before mu so the last counters are written last
+	fileMu sync.Mutex
+}
+
+// budgetUsage is the credits spent by a key in the current day and month, persisted by the fingerprint of the key
+type budgetUsage struct {
+	Day          string `json:"day"`
+	DayCredits   int    `json:"day_credits"`
+	Month        string `json:"month"`
+	MonthCredits int    `json:"month_credits"`
+}
+
+// BudgetUsage is the credits spent by a key and the caps
+type BudgetUsage struct {
+	CreditsToday     int `json:"credits_today"`
+	CreditsThisMonth int `json:"credits_this_month"`
+	DailyCap         int `json:"daily_cap,omitempty"`
+	MonthlyCap       int `json:"monthly_cap,omitempty"`
+}
+
+// NewBudget create the budget, the counters are loaded from the file if it exists
+func NewBudget(opts BudgetOptions) (*Budget, error) {
+	if opts.Daily < 0 || opts.Monthly < 0 {
+		return nil, errors.New("tavily budget error: caps must not be negative")
+	}
+	if opts.Policy == "" {
+		opts.Policy = BudgetRefuse
+	}
+	if opts.Policy != BudgetRefuse && opts.Policy != BudgetDowngrade {
+		return nil, fmt.Errorf("tavily budget error: %s is not a valid policy, use %s or %s", opts.Policy, BudgetRefuse, BudgetDowngrade)
+	}
+
+	b := &Budget{opts: opts, usage: map[string]*budgetUsage{}, now: time.Now}
+	if opts.File != "" {
+		data, err := os.ReadFile(opts.File)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("tavily budget error: %v", err)
+		default:
+			if err := json.Unmarshal(data, &b.usage); err != nil {
+				return nil, fmt.Errorf("tavily budget error: %s is not a valid budget file: %v", opts.File, err)
+			}
+		}
+	}
+	return b, nil
+}
+
+// Policy return the policy of the calls exceeding the budget
+func (b *Budget) Policy() string {
+	return b.opts.Policy
+}
+
+// Allows return true if the key can spend the credits without exceeding the caps
+func (b *Budget) Allows(key string, credits int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.check(key, credits) == nil
+}
+
+// AllowsAny return true if any of the keys can spend the credits
+func (b *Budget) AllowsAny(keys []string, credits int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range keys {
+		if b.check(key, credits) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Exceeded return the error of the keys which can not spend the credits, the one resetting first is reported
+func (b *Budget) Exceeded(keys []string, credits int) *BudgetExceededError {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var first *BudgetExceededError
+	for _, key := range keys {
+		err := b.check(key, credits)
+		if err == nil {
+			return nil
+		}
+		if first == nil || err.ResetAt.Before(first.ResetAt) {
+			first = err
+		}
+	}
+	if first != nil {
+		first.Keys = len(keys)
+	}
+	return first
+}
+
+// Reserve add the credits to the key if it does not exceed the caps
+func (b *Budget) Reserve(key string, credits int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.check(key, credits); err != nil {
+		err.Keys = 1
+		return err
+	}
+	b.add(key, credits)
+	return nil
+}
+
+// Settle replace the reserved credits of the key by the actual credits, then persist the counters
+// if they were not written in the last budgetSaveInterval
+func (b *Budget) Settle(key string, reserved, actual int) error {
+	b.mu.Lock()
+	if actual != reserved {
+		b.add(key, actual-reserved)
+	}
+	due := b.dirty && b.now().Sub(b.saved) >= budgetSaveInterval
+	b.mu.Unlock()
+	if !due {
+		return nil
+	}
+	return b.save()
+}
+
+// Flush persist the counters changed since they were last written, call it before the process exits
+func (b *Budget) Flush() error {
+	return b.save()
+}
+
+// Usage return the credits spent by the key
+func (b *Budget) Usage(key string) BudgetUsage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	u := b.current(key)
+	return BudgetUsage{
+		CreditsToday:     u.DayCredits,
+		CreditsThisMonth: u.MonthCredits,
+		DailyCap:         b.opts.Daily,
+		MonthlyCap:       b.opts.Monthly,
+	}
+}
+
+// check return the error if the key can not spend the credits, the lock must be held
+func (b *Budget) check(key string, credits int) *BudgetExceededError {
+	u := b.current(key)
+	now := b.now().UTC()
+	if b.opts.Daily > 0 && u.DayCredits+credits > b.opts.Daily {
+		return &BudgetExceededError{
+			Period:  BudgetDaily,
+			Cap:     b.opts.Daily,
+			Used:    u.DayCredits,
+			Credits: credits,
+			ResetAt: time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	if b.opts.Monthly > 0 && u.MonthCredits+credits > b.opts.Monthly {
+		return &BudgetExceededError{
+			Period:  BudgetMonthly,
+			Cap:     b.opts.Monthly,
+			Used:    u.MonthCredits,
+			Credits: credits,
+			ResetAt: time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	return nil
+}
+
+// add add the credits to the counters of the key, the lock must be held
+func (b *Budget) add(key string, credits int) {
+	u := b.current(key)
+	u.DayCredits = max(u.DayCredits+credits, 0)
+	u.MonthCredits = max(u.MonthCredits+credits, 0)
+	b.dirty = true
+}
+
+// current return the counters of the key, reset when the day or the month has passed, the lock must be held
+func (b *Budget) current(key string) *budgetUsage {
+	id := keyFingerprint(key)
+	u, ok := b.usage[id]
+	if !ok {
+		u = &budgetUsage{}
+		b.usage[id] = u
+	}
+	now := b.now().UTC()
+	if day := now.Format(time.DateOnly); u.Day != day {
+		u.Day, u.DayCredits = day, 0
+	}
+	if month := now.Format("2006-01"); u.Month != month {
+		u.Month, u.MonthCredits = month, 0
+	}
+	return u
+}
+
+// save write the counters to the file if they changed, the counters are locked while they are encoded only,
+// so the calls do not wait on the disk
+func (b *Budget) save() error {
+	if b.opts.File == "" {
+		return nil
+	}
+	b.fileMu.Lock()
+	defer b.fileMu.Unlock()
+
+	b.mu.Lock()
+	if !b.dirty {
+		b.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(b.usage, "", "  ")
+	if err == nil {
+		b.dirty, b.saved = false, b.now()
+	}
+	b.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("tavily budget error: %v", err)
+	}
+	if err := writeBudgetFile(b.opts.File, data); err != nil {
+		// the counters are written again by the next save
+		b.mu.Lock()
+		b.dirty = true
+		b.mu.Unlock()
+		return fmt.Errorf("tavily budget error: %v", err)
+	}
+	return nil
+}
+
+// writeBudgetFile write to a temp file then rename it, a crash never leaves a truncated file
+func writeBudgetFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// keyFingerprint identify the key in the budget file without storing the key itself
+func keyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// WithBudget set the credit budget of the api keys, requests exceeding it are refused before they are sent
+func WithBudget(budget *Budget) ClientOption {
+	return func(c *Client) error {
+		c.budget = budget
+		return nil
+	}
+}
+
+// BudgetExceededError means the call would exceed the credit budget of the api keys
+type BudgetExceededError struct {
+	// Keys is the number of keys exceeding the budget
+	Keys int
+	// Period is daily or monthly
+	Period string
+	Cap    int
+	Used   int
+	// Credits is the estimated credits of the call
+	Credits int
+	// ResetAt is when the period of the budget starts again
+	ResetAt time.Time
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("tavily budget error: the call needs %d credits, the %s budget of the api key has %d of %d credits left, it resets at %s",
+		e.Credits, e.Period, max(e.Cap-e.Used, 0), e.Cap, e.ResetAt.Format(time.RFC3339))
+}
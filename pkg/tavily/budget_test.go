@@ -0,0 +1,262 @@
+package tavily_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily/tavilytest"
+)
+
+func TestBudgetWindows(t *testing.T) {
+	budget, err := tavily.NewBudget(tavily.BudgetOptions{Daily: 10, Monthly: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, time.January, 29, 23, 0, 0, 0, time.UTC)
+	budget.SetNow(func() time.Time { return now })
+	const key = "tvly-budget-key"
+
+	reserve := func(credits int, wantPeriod string) {
+		t.Helper()
+		err := budget.Reserve(key, credits)
+		if wantPeriod == "" {
+			if err != nil {
+				t.Fatalf("%s: reserve %d: %v", now.Format(time.DateOnly), credits, err)
+			}
+			return
+		}
+		var budgetErr *tavily.BudgetExceededError
+		if !errors.As(err, &budgetErr) || budgetErr.Period != wantPeriod {
+			t.Fatalf("%s: reserve %d: got %v, want the %s budget exceeded", now.Format(time.DateOnly), credits, err, wantPeriod)
+		}
+	}
+
+	reserve(8, "")
+	reserve(3, tavily.BudgetDaily)
+	now = now.Add(2 * time.Hour) // the next day
+	reserve(10, "")
+	now = now.Add(24 * time.Hour)
+	reserve(7, "")
+	reserve(1, tavily.BudgetMonthly)
+	if usage := budget.Usage(key); usage.CreditsToday != 7 || usage.CreditsThisMonth != 25 {
+		t.Errorf("got usage %+v, want 7 today and 25 this month", usage)
+	}
+
+	err = budget.Reserve(key, 1)
+	var budgetErr *tavily.BudgetExceededError
+	if !errors.As(err, &budgetErr) || !budgetErr.ResetAt.Equal(time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %v, want the monthly budget reset on february 1st", err)
+	}
+	now = time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)
+	reserve(10, "")
+
+	// settle replaces the reserved credits by the actual ones
+	if err := budget.Settle(key, 10, 4); err != nil {
+		t.Fatal(err)
+	}
+	if usage := budget.Usage(key); usage.CreditsToday != 4 {
+		t.Errorf("got %d credits today, want 4 after the settle", usage.CreditsToday)
+	}
+}
+
+// newBudgetClient return a client of the fake with the budget, the credits are spent on the keys beforehand
+func newBudgetClient(t *testing.T, fake *tavilytest.Server, opts tavily.BudgetOptions, spent map[string]int) *tavily.Client {
+	t.Helper()
+	budget, err := tavily.NewBudget(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 0, len(spent))
+	for key, credits := range spent {
+		keys = append(keys, key)
+		if err := budget.Reserve(key, credits); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := fake.Client(keys...)
+	if err := tavily.WithBudget(budget)(client); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestBudgetPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         string
+		depth          string
+		spent          int
+		wantErr        bool
+		wantDowngraded bool
+	}{
+		{name: "within budget", policy: tavily.BudgetRefuse, depth: tavily.DepthAdvanced, spent: 0},
+		{name: "refused", policy: tavily.BudgetRefuse, depth: tavily.DepthAdvanced, spent: 1, wantErr: true},
+		{name: "downgraded", policy: tavily.BudgetDowngrade, depth: tavily.DepthAdvanced, spent: 1, wantDowngraded: true},
+		{name: "downgrade refused", policy: tavily.BudgetDowngrade, depth: tavily.DepthAdvanced, spent: 2, wantErr: true},
+		{name: "basic refused", policy: tavily.BudgetDowngrade, depth: tavily.DepthBasic, spent: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := tavilytest.NewServer()
+			defer fake.Close()
+			client := newBudgetClient(t, fake, tavily.BudgetOptions{Daily: 2, Policy: tt.policy}, map[string]int{"tvly-budget-key": tt.spent})
+
+			res, err := client.Search(context.Background(), "golang", tavily.WithSearchDepth(tt.depth))
+			if tt.wantErr {
+				var budgetErr *tavily.BudgetExceededError
+				if !errors.As(err, &budgetErr) {
+					t.Fatalf("got %v, want the budget exceeded", err)
+				}
+				if n := len(fake.Requests()); n != 0 {
+					t.Errorf("got %d requests, want none", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Downgraded != tt.wantDowngraded {
+				t.Errorf("got downgraded %v, want %v", res.Downgraded, tt.wantDowngraded)
+			}
+			wantDepth := tt.depth
+			if tt.wantDowngraded {
+				wantDepth = tavily.DepthBasic
+			}
+			if got := fake.Requests()[0].Body["search_depth"]; got != wantDepth {
+				t.Errorf("got search depth %v, want %s", got, wantDepth)
+			}
+		})
+	}
+}
+
+func TestBudgetBenchedKey(t *testing.T) {
+	fake := tavilytest.NewServer()
+	defer fake.Close()
+	// the first key has the budget but is benched, the second one is over the budget
+	client := newBudgetClient(t, fake, tavily.BudgetOptions{Daily: 2, Policy: tavily.BudgetDowngrade},
+		map[string]int{"tvly-benched-key": 0, "tvly-spent-key": 2})
+	client.Keys.Report("tvly-benched-key", 401, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := client.Search(ctx, "golang", tavily.WithSearchDepth(tavily.DepthAdvanced))
+	var budgetErr *tavily.BudgetExceededError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("got %v, want the budget exceeded", err)
+	}
+	if budgetErr.Keys != 1 {
+		t.Errorf("got %d keys exceeded, want only the key out of the cool-down", budgetErr.Keys)
+	}
+	if ctx.Err() != nil {
+		t.Error("the search did not return before the timeout")
+	}
+}
+
+func TestBudgetFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "budget.json")
+	budget, err := tavily.NewBudget(tavily.BudgetOptions{Daily: 100, File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	budget.SetNow(func() time.Time { return now })
+	const key = "tvly-budget-key"
+
+	// persisted return the credits of today in the file
+	persisted := func() int {
+		t.Helper()
+		loaded, err := tavily.NewBudget(tavily.BudgetOptions{Daily: 100, File: file})
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded.SetNow(func() time.Time { return now })
+		return loaded.Usage(key).CreditsToday
+	}
+	call := func(reserved, actual int) {
+		t.Helper()
+		if err := budget.Reserve(key, reserved); err != nil {
+			t.Fatal(err)
+		}
+		if err := budget.Settle(key, reserved, actual); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	call(3, 3)
+	if got := persisted(); got != 3 {
+		t.Errorf("got %d credits in the file, want the first settle written", got)
+	}
+	now = now.Add(time.Second)
+	call(2, 1)
+	if got := persisted(); got != 3 {
+		t.Errorf("got %d credits in the file, want the settle within the interval not written", got)
+	}
+	now = now.Add(5 * time.Second)
+	call(1, 1)
+	if got := persisted(); got != 5 {
+		t.Errorf("got %d credits in the file, want the settles since the last write written together", got)
+	}
+	now = now.Add(time.Second)
+	call(4, 4)
+	if err := budget.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := persisted(); got != 9 {
+		t.Errorf("got %d credits in the file, want the flush to write the last settle", got)
+	}
+
+	// nothing changed since the flush, the file is not written again
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := budget.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v, want the unchanged counters not written", err)
+	}
+}
+
+func TestBudgetCrawlLimit(t *testing.T) {
+	// a crawl without a page limit is reserved for the 50 pages tavily processes by default
+	unlimited := tavily.CrawlCredits(tavily.DepthBasic, 50, false)
+	tests := []struct {
+		name    string
+		crawl   bool
+		h       []tavily.WithOptionHelper
+		daily   int
+		wantErr bool
+	}{
+		{name: "crawl without limit", crawl: true, daily: unlimited - 1, wantErr: true},
+		{name: "crawl without limit within budget", crawl: true, daily: unlimited},
+		{name: "crawl with limit", crawl: true, h: []tavily.WithOptionHelper{tavily.WithPageLimit(5)}, daily: unlimited - 1},
+		{name: "map without limit", daily: tavily.MapCredits(50, false) - 1, wantErr: true},
+		{name: "map with limit", h: []tavily.WithOptionHelper{tavily.WithPageLimit(5)}, daily: tavily.MapCredits(50, false) - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := tavilytest.NewServer()
+			defer fake.Close()
+			client := newBudgetClient(t, fake, tavily.BudgetOptions{Daily: tt.daily}, map[string]int{"tvly-budget-key": 0})
+
+			var err error
+			if tt.crawl {
+				_, err = client.Crawl(context.Background(), "https://go.dev", tt.h...)
+			} else {
+				_, err = client.Map(context.Background(), "https://go.dev", tt.h...)
+			}
+			var budgetErr *tavily.BudgetExceededError
+			if got := errors.As(err, &budgetErr); got != tt.wantErr {
+				t.Fatalf("got %v, want the budget exceeded %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
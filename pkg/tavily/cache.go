@@ -0,0 +1,293 @@
+package tavily
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CacheNone   = "none"
+	CacheMemory = "memory"
+	CacheDisk   = "disk"
+
+	DefaultCacheSize       = 256
+	DefaultNewsCacheTTL    = 5 * time.Minute
+	DefaultGeneralCacheTTL = time.Hour
+
+	// diskSweepInterval is the min time between two sweeps of the disk cache
+	diskSweepInterval = 10 * time.Minute
+	// diskTempMaxAge is the age of the temp files left by a crash, they are removed by the sweep
+	diskTempMaxAge = time.Hour
+)
+
+// Cache store the raw tavily response by the request key
+type Cache interface {
+	// Get return the cached value of key, ok is false when the key is missing or expired
+	Get(key string) (value []byte, ok bool)
+	// Set store value with key, the value expires after ttl
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// CacheTTL is the ttl of cached responses by topic
+type CacheTTL map[string]time.Duration
+
+// DefaultCacheTTL return the default ttl, news results expire sooner than general ones
+func DefaultCacheTTL() CacheTTL {
+	return CacheTTL{
+		TopicNews:    DefaultNewsCacheTTL,
+		TopicGeneral: DefaultGeneralCacheTTL,
+	}
+}
+
+// Get return the ttl of topic, zero means the topic is not cached
+func (c CacheTTL) Get(topic string) time.Duration {
+	return c[topic]
+}
+
+// cacheKey return the key of the search request, the api key is left out of it
+func cacheKey(req TavilySearchResquest) string {
+	req.ApiKey = ""
+	req.Query = strings.ToLower(strings.Join(strings.Fields(req.Query), " "))
+	req.IncludeDomains = normalizeDomains(req.IncludeDomains)
+	req.ExcludeDomains = normalizeDomains(req.ExcludeDomains)
+
+	b, _ := json.Marshal(req)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeDomains(domains []string) []string {
+	if len(domains) == 0 {
+		return nil
+	}
+	res := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" {
+			res = append(res, domain)
+		}
+	}
+	slices.Sort(res)
+	return slices.Compact(res)
+}
+
+// MemoryCache is a in-memory lru cache
+type MemoryCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryCache return a lru cache holding at most size entries
+func NewMemoryCache(size int) *MemoryCache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &MemoryCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*memoryCacheEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&memoryCacheEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// DiskCache store each entry as a json file in the dir, the expired entries are swept on open and periodically,
+// and the oldest entries are removed when there are more than the max entries
+type DiskCache struct {
+	dir        string
+	maxEntries int
+
+	sweeping  sync.Mutex
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+type diskCacheEntry struct {
+	ExpiresAt time.Time       `json:"expires_at"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// NewDiskCache return a cache storing at most maxEntries entries in dir, the dir is created if not exists,
+// maxEntries <= 0 means DefaultCacheSize
+func NewDiskCache(dir string, maxEntries int) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir %s: %v", dir, err)
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheSize
+	}
+	c := &DiskCache{dir: dir, maxEntries: maxEntries}
+	c.Sweep()
+	return c, nil
+}
+
+// Get
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry diskCacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		os.Remove(c.path(key))
+		return nil, false
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		os.Remove(c.path(key))
+		return nil, false
+	}
+	return entry.Value, true
+}
+
+// Set
+func (c *DiskCache) Set(key string, value []byte, ttl time.Duration) {
+	b, err := json.Marshal(diskCacheEntry{ExpiresAt: time.Now().Add(ttl), Value: value})
+	if err != nil {
+		return
+	}
+	// write to a temp file and rename it, so readers never see a partial entry
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		return
+	}
+	os.Rename(tmp.Name(), c.path(key))
+
+	c.mu.Lock()
+	due := time.Since(c.lastSweep) > diskSweepInterval
+	if due {
+		c.lastSweep = time.Now()
+	}
+	c.mu.Unlock()
+	if due {
+		go c.Sweep()
+	}
+}
+
+// Sweep remove the expired and corrupted entries and the stale temp files,
+// then the oldest entries over the max entries
+func (c *DiskCache) Sweep() {
+	// a sweep in progress is enough
+	if !c.sweeping.TryLock() {
+		return
+	}
+	defer c.sweeping.Unlock()
+	c.mu.Lock()
+	c.lastSweep = time.Now()
+	c.mu.Unlock()
+
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	type kept struct {
+		path    string
+		modTime time.Time
+	}
+	var entries []kept
+	now := time.Now()
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			continue
+		}
+		path := filepath.Join(c.dir, dirEntry.Name())
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(dirEntry.Name(), ".tmp"):
+			if now.Sub(info.ModTime()) > diskTempMaxAge {
+				os.Remove(path)
+			}
+		case strings.HasSuffix(dirEntry.Name(), ".json"):
+			b, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			var entry diskCacheEntry
+			if err := json.Unmarshal(b, &entry); err != nil || now.After(entry.ExpiresAt) {
+				os.Remove(path)
+				continue
+			}
+			entries = append(entries, kept{path: path, modTime: info.ModTime()})
+		}
+	}
+
+	if len(entries) <= c.maxEntries {
+		return
+	}
+	slices.SortFunc(entries, func(a, b kept) int {
+		return a.modTime.Compare(b.modTime)
+	})
+	for _, entry := range entries[:len(entries)-c.maxEntries] {
+		os.Remove(entry.path)
+	}
+}
+
+func (c *DiskCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
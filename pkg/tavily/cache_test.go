@@ -0,0 +1,56 @@
+package tavily
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDiskCacheSweep(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewDiskCache(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 5 {
+		c.Set(fmt.Sprint(i), []byte(`"value"`), time.Hour)
+		// the oldest entries are removed first by the modification time
+		past := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(c.path(fmt.Sprint(i)), past, past)
+	}
+	c.Set("expired", []byte(`"value"`), -time.Second)
+	c.Sweep()
+
+	for key, want := range map[string]bool{"0": false, "1": false, "2": true, "3": true, "4": true, "expired": false} {
+		if _, err := os.Stat(c.path(key)); (err == nil) != want {
+			t.Errorf("entry %s exists = %v, want %v", key, err == nil, want)
+		}
+	}
+	if _, ok := c.Get("4"); !ok {
+		t.Error("entry 4 is not served after the sweep")
+	}
+}
+
+func TestDiskCacheSweepOnOpen(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewDiskCache(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("expired", []byte(`"value"`), -time.Second)
+	if err := os.WriteFile(c.path("corrupted"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewDiskCache(dir, 10); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("got %d files after open, want the expired and corrupted entries removed", len(entries))
+	}
+}
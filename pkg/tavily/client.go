@@ -0,0 +1,385 @@
+package tavily
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Client is a tavily api client, create it by NewClient, it is safe for concurrent use
+type Client struct {
+	Keys *KeyPool
+
+	IncludeDomains []string
+	ExcludeDomains []string
+
+	logger         *slog.Logger
+	metrics        Metrics
+	tracerProvider trace.TracerProvider
+	budget         *Budget
+
+	cache    Cache
+	cacheTTL CacheTTL
+	flights  flightGroup
+
+	// MaxRetries is the max times a failed request is sent again
+	MaxRetries int
+	// RetryBaseDelay is the backoff delay of the first retry, it doubles on each retry
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay is the max backoff delay, longer Retry-After is not waited
+	RetryMaxDelay time.Duration
+
+	// BaseURL is the base url of the tavily api, endpoints are joined to it
+	BaseURL string
+	// HTTPClient is the client sending requests to tavily
+	HTTPClient *http.Client
+}
+
+// ClientOption configure the client created by NewClient
+type ClientOption func(*Client) error
+
+// NewClient create a tavily api client, an api key is required, set by WithAPIKeys or WithKeyPool
+func NewClient(opts ...ClientOption) (*Client, error) {
+	c := &Client{
+		MaxRetries:     DefaultMaxRetries,
+		RetryBaseDelay: DefaultRetryBaseDelay,
+		RetryMaxDelay:  DefaultRetryMaxDelay,
+		BaseURL:        DefaultBaseURL,
+		HTTPClient:     http.DefaultClient,
+		logger:         slog.New(discardHandler{}),
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.Keys == nil || c.Keys.Len() == 0 {
+		return nil, errors.New("tavily client error: api key is required")
+	}
+	return c, nil
+}
+
+// WithAPIKeys set the api keys, they are rotated round-robin, use WithKeyPool for the other strategies
+func WithAPIKeys(keys ...string) ClientOption {
+	return func(c *Client) error {
+		pool, err := NewKeyPool(keys, KeyStrategyRoundRobin, DefaultKeyCooldown)
+		if err != nil {
+			return err
+		}
+		c.Keys = pool
+		return nil
+	}
+}
+
+// WithKeyPool set the pool of the api keys
+func WithKeyPool(pool *KeyPool) ClientOption {
+	return func(c *Client) error {
+		c.Keys = pool
+		return nil
+	}
+}
+
+// WithBaseURL set the base url of the tavily api, like a local fake for tests
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) error {
+		if u, err := url.Parse(baseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("tavily base url error: %s is not a valid http(s) url", baseURL)
+		}
+		c.BaseURL = baseURL
+		return nil
+	}
+}
+
+// WithHTTPClient set the http client sending requests to tavily
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) error {
+		c.HTTPClient = httpClient
+		return nil
+	}
+}
+
+// WithHTTPOptions set the http client built from the timeouts, proxy and tls options
+func WithHTTPOptions(opts HTTPOptions) ClientOption {
+	return func(c *Client) error {
+		httpClient, err := NewHTTPClient(opts)
+		if err != nil {
+			return err
+		}
+		c.HTTPClient = httpClient
+		return nil
+	}
+}
+
+// WithDomainPolicy set the domains every search is limited to and excluded from, the per-search domains are merged with them
+func WithDomainPolicy(include, exclude []string) ClientOption {
+	return func(c *Client) error {
+		c.IncludeDomains = include
+		c.ExcludeDomains = exclude
+		return nil
+	}
+}
+
+// WithCache enable the response cache of search, topics without ttl are not cached
+func WithCache(cache Cache, ttl CacheTTL) ClientOption {
+	return func(c *Client) error {
+		c.SetCache(cache, ttl)
+		return nil
+	}
+}
+
+// WithRetry set the max retries and the backoff delays of the failed requests
+func WithRetry(maxRetries int, baseDelay, maxDelay time.Duration) ClientOption {
+	return func(c *Client) error {
+		if maxRetries < 0 || baseDelay < 0 || maxDelay < 0 {
+			return errors.New("tavily retry error: retries and delays must not be negative")
+		}
+		c.MaxRetries = maxRetries
+		c.RetryBaseDelay = baseDelay
+		c.RetryMaxDelay = maxDelay
+		return nil
+	}
+}
+
+// WithLogger set the logger of the key pool and retry events, the request and response bodies are logged at debug level
+// with the api key masked, nothing is logged by default
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(c *Client) error {
+		if logger == nil {
+			logger = slog.New(discardHandler{})
+		}
+		c.logger = logger
+		return nil
+	}
+}
+
+// SetCache enable the response cache of search, topics without ttl are not cached
+func (c *Client) SetCache(cache Cache, ttl CacheTTL) {
+	c.cache = cache
+	c.cacheTTL = ttl
+}
+
+// apiKeyRequest is the request body carrying the api key
+type apiKeyRequest interface {
+	setApiKey(key string)
+}
+
+// post send the request body to the tavily endpoint and unmarshal the response into out.
+// when the key is benched by the pool, the request is sent again with the next available key at once,
+// network failures, 429 and 5xx are retried with jittered exponential backoff, honoring Retry-After.
+func (c *Client) post(ctx context.Context, endpoint string, cost requestCost, in apiKeyRequest, out any) error {
+	var lastErr error
+	retries := 0
+	// picks is the times a key is picked again after the budget changed under the request
+	picks := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			if lastErr != nil {
+				return lastErr
+			}
+			return err
+		}
+		key, err := c.Keys.acquire(c.allowKey(cost.estimate))
+		var notAllowed *keysNotAllowedError
+		if errors.As(err, &notAllowed) {
+			// only the keys acquire could hand out count, the benched keys are left to the cool-down
+			if err := c.budget.Exceeded(notAllowed.keys, cost.estimate); err != nil {
+				return err
+			}
+			// a key got its budget back since it was rejected, pick again
+			if picks++; picks > c.Keys.Len() {
+				return notAllowed
+			}
+			continue
+		}
+		if err != nil {
+			// all keys are cooling down, wait for the first one if it is soon enough
+			wait := c.Keys.NextAvailable()
+			if lastErr == nil || !isRetryable(lastErr) || retries >= c.MaxRetries || wait > c.RetryMaxDelay {
+				if lastErr != nil {
+					return lastErr
+				}
+				return err
+			}
+			if err := sleep(ctx, wait); err != nil {
+				return lastErr
+			}
+			retries++
+			continue
+		}
+		if c.budget != nil {
+			if err := c.budget.Reserve(key, cost.estimate); err != nil {
+				// another request took the budget of the key since it was acquired, pick again
+				if picks++; picks > c.Keys.Len() {
+					return err
+				}
+				continue
+			}
+		}
+		in.setApiKey(key)
+
+		err = c.do(ctx, endpoint, key, in, out)
+		c.settle(ctx, key, cost, err)
+		if err == nil {
+			c.Keys.Report(key, http.StatusOK, 0)
+			return nil
+		}
+		lastErr = err
+
+		if status := statusOf(err); status != 0 {
+			bench := retryAfterOf(err)
+			if status == http.StatusTooManyRequests && bench == 0 {
+				// a rate limited key is benched for the backoff only, once all the keys are benched
+				// the request waits for the first one, like a retry
+				bench = c.backoff(retries, 0)
+			}
+			if c.Keys.Report(key, status, bench) {
+				c.logger.WarnContext(ctx, "tavily api key benched", "key", MaskKey(key), "status", status)
+				trace.SpanFromContext(ctx).AddEvent("key benched", trace.WithAttributes(attribute.Int("http.status", status)))
+				continue
+			}
+		}
+
+		if !isRetryable(err) || retries >= c.MaxRetries {
+			return err
+		}
+		delay := c.backoff(retries, retryAfterOf(err))
+		if delay > c.RetryMaxDelay {
+			return err
+		}
+		c.logger.InfoContext(ctx, "tavily api retry", "endpoint", endpoint, "delay", delay, "retry", retries+1, "error", err)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(attribute.Int("retry", retries+1), attribute.String("delay", delay.String()), attribute.String("error", err.Error())))
+		if err := sleep(ctx, delay); err != nil {
+			return lastErr
+		}
+		retries++
+	}
+}
+
+// requestCost is the credits of a request, the estimate is reserved from the budget before the request is sent,
+// and replaced by the actual credits computed from the response once it succeeded, failed requests cost nothing
+type requestCost struct {
+	estimate int
+	// actual return the credits by the response, nil means the estimate is exact
+	actual func() int
+}
+
+// allowKey return the filter of the keys having the budget for the credits, nil if there is no budget
+func (c *Client) allowKey(credits int) func(key string) bool {
+	if c.budget == nil {
+		return nil
+	}
+	return func(key string) bool {
+		return c.budget.Allows(key, credits)
+	}
+}
+
+// settle replace the reserved credits of the key by the actual credits of the request
+func (c *Client) settle(ctx context.Context, key string, cost requestCost, err error) {
+	if c.budget == nil {
+		return
+	}
+	actual := 0
+	if err == nil {
+		actual = cost.estimate
+		if cost.actual != nil {
+			actual = cost.actual()
+		}
+	}
+	if err := c.budget.Settle(key, cost.estimate, actual); err != nil {
+		c.logger.ErrorContext(ctx, "tavily budget save error", "error", err)
+	}
+}
+
+// do send one request to the tavily endpoint, the endpoint is the path relative to the base url
+func (c *Client) do(ctx context.Context, endpoint string, key string, in any, out any) error {
+	var body io.Reader
+	reqbody, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("tavily params marshal error: %v", err)
+	}
+	body = strings.NewReader(string(reqbody))
+
+	if c.logger.Enabled(ctx, slog.LevelDebug) {
+		c.logger.DebugContext(ctx, "tavily api request", "endpoint", endpoint, "body", redactBody(reqbody))
+	}
+	start := time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.BaseURL, "/")+endpoint, body)
+	if err != nil {
+		return fmt.Errorf("tavily api request error: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		c.observeRequest(endpoint, 0, time.Since(start))
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &NetworkError{Err: err}
+	}
+	defer resp.Body.Close()
+	defer func() {
+		c.observeRequest(endpoint, resp.StatusCode, time.Since(start))
+	}()
+
+	// 读取响应体
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &NetworkError{Err: fmt.Errorf("failed to read Tavily API response: %w", err)}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp, respBody)
+	}
+
+	// 解析响应
+	c.logger.DebugContext(ctx, "tavily api response", "endpoint", endpoint, "status", resp.StatusCode,
+		"latency", time.Since(start), "bytes", len(respBody))
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to unmarshal Tavily API response: %v", err)
+	}
+	return nil
+}
+
+// redactBody return the request body with the api key masked, for logging
+func redactBody(body []byte) any {
+	var m map[string]any
+	if err := json.Unmarshal(body, &m); err != nil {
+		return string(body)
+	}
+	if key, ok := m["api_key"].(string); ok {
+		m["api_key"] = MaskKey(key)
+	}
+	return m
+}
+
+// discardHandler drop every log record
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// KeyUsage return the usage counters of each api key, with the credits spent if there is a budget, the keys are masked
+func (c *Client) KeyUsage() []KeyUsage {
+	usage := c.Keys.Usage()
+	if c.budget != nil {
+		for i, key := range c.Keys.Keys() {
+			budget := c.budget.Usage(key)
+			usage[i].Budget = &budget
+		}
+	}
+	return usage
+}
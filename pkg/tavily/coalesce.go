@@ -0,0 +1,74 @@
+package tavily
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup coalesce the identical searches in flight, only the first one is sent to tavily
+// and the others wait for its response. the zero value is ready to use
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is a search in flight, it is cancelled when all its callers are gone
+type flight struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	callers int
+
+	resp *TavilySearchResponse
+	err  error
+}
+
+// do run fn once for the identical calls of key in flight, and return its response to each caller.
+// fn runs with a context keeping the values of the first caller, it is cancelled only when every caller is cancelled,
+// a cancelled caller returns its own context error right away. shared is true if the response is from the call of another caller
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (*TavilySearchResponse, error)) (resp *TavilySearchResponse, shared bool, err error) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = map[string]*flight{}
+	}
+	f, shared := g.flights[key]
+	if shared {
+		f.callers++
+	} else {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel, callers: 1}
+		g.flights[key] = f
+		go func() {
+			defer cancel()
+			f.resp, f.err = fn(flightCtx)
+			g.mu.Lock()
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+			g.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return nil, shared, f.err
+		}
+		// each caller gets its own copy, the slices are shared and must not be modified
+		res := *f.resp
+		return &res, shared, nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.callers--
+		if f.callers == 0 {
+			f.cancel()
+			// the cancelled flight must not be joined by the next identical search
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, shared, ctx.Err()
+	}
+}
@@ -0,0 +1,168 @@
+package tavily_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily/tavilytest"
+)
+
+// waitRequests wait until the fake received n requests
+func waitRequests(t *testing.T, fake *tavilytest.Server, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(fake.Requests()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d requests, want %d", len(fake.Requests()), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestIdenticalSearchesCoalesced(t *testing.T) {
+	fake := tavilytest.NewServer()
+	defer fake.Close()
+	fake.SetLatency(100 * time.Millisecond)
+	client := fake.Client()
+
+	const callers = 5
+	var wg sync.WaitGroup
+	results := make([]*tavily.TavilySearchResponse, callers)
+	errs := make([]error, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = client.Search(context.Background(), "golang", tavily.WithMaxResults(3))
+		}()
+	}
+	wg.Wait()
+
+	shared := 0
+	for i := range callers {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if len(results[i].Results) != 3 {
+			t.Errorf("caller %d got %d results, want 3", i, len(results[i].Results))
+		}
+		if results[i].Shared {
+			shared++
+		}
+	}
+	if got := len(fake.Requests()); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+	if shared != callers-1 {
+		t.Errorf("got %d shared responses, want %d", shared, callers-1)
+	}
+
+	// the response is a copy, changing it does not change the response of the others
+	results[0].Query = "changed"
+	for i := 1; i < callers; i++ {
+		if results[i].Query != "golang" {
+			t.Errorf("caller %d got query %s, want golang", i, results[i].Query)
+		}
+	}
+}
+
+func TestDifferentSearchesNotCoalesced(t *testing.T) {
+	fake := tavilytest.NewServer()
+	defer fake.Close()
+	fake.SetLatency(50 * time.Millisecond)
+	client := fake.Client()
+
+	var wg sync.WaitGroup
+	for _, h := range [][]tavily.WithOptionHelper{
+		{tavily.WithMaxResults(3)},
+		{tavily.WithMaxResults(4)},
+		{tavily.WithMaxResults(3), tavily.WithTopic(tavily.TopicNews)},
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if res, err := client.Search(context.Background(), "golang", h...); err != nil {
+				t.Error(err)
+			} else if res.Shared {
+				t.Error("a different search got a shared response")
+			}
+		}()
+	}
+	wg.Wait()
+	if got := len(fake.Requests()); got != 3 {
+		t.Errorf("got %d requests, want 3", got)
+	}
+}
+
+func TestCoalescedCallerCancelled(t *testing.T) {
+	fake := tavilytest.NewServer()
+	defer fake.Close()
+	fake.SetLatency(200 * time.Millisecond)
+	client := fake.Client()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := client.Search(ctx, "golang")
+		first <- err
+	}()
+	waitRequests(t, fake, 1)
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := client.Search(context.Background(), "golang")
+		second <- err
+	}()
+	// the first caller gives up, the search goes on for the second one
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-first:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want context canceled", err)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("the cancelled caller did not return at once")
+	}
+	if err := <-second; err != nil {
+		t.Errorf("the waiting caller got %v", err)
+	}
+	if got := len(fake.Requests()); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+}
+
+func TestCoalescedSearchCancelled(t *testing.T) {
+	fake := tavilytest.NewServer()
+	defer fake.Close()
+	fake.SetLatency(200 * time.Millisecond)
+	client := fake.Client()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Search(ctx, "golang")
+		done <- err
+	}()
+	waitRequests(t, fake, 1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context canceled", err)
+	}
+
+	// every caller is gone, the next identical search must not join the cancelled one
+	res, err := client.Search(context.Background(), "golang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Shared {
+		t.Error("the search joined the cancelled one")
+	}
+	if got := len(fake.Requests()); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+}
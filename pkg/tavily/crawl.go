@@ -0,0 +1,211 @@
+package tavily
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/param"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	TavilyCrawlEndpoint = "/crawl"
+	TavilyMapEndpoint   = "/map"
+	// MaxCrawlDepth is the max depth tavily crawl and map follow links to
+	MaxCrawlDepth = 5
+	// defaultCrawlLimit is the pages tavily crawl and map process when the request has no limit
+	defaultCrawlLimit = 50
+)
+
+// TavilyCrawlRequest is the request of crawl and map, the domains are regular expressions
+type TavilyCrawlRequest struct {
+	URL            string   `json:"url"`
+	MaxDepth       int      `json:"max_depth,omitempty"`
+	MaxBreadth     int      `json:"max_breadth,omitempty"`
+	Limit          int      `json:"limit,omitempty"`
+	Instructions   string   `json:"instructions,omitempty"`
+	SelectPaths    []string `json:"select_paths,omitempty"`
+	ExcludePaths   []string `json:"exclude_paths,omitempty"`
+	SelectDomains  []string `json:"select_domains,omitempty"`
+	ExcludeDomains []string `json:"exclude_domains,omitempty"`
+	AllowExternal  *bool    `json:"allow_external,omitempty"`
+	IncludeImages  bool     `json:"include_images,omitempty"`
+	ExtractDepth   string   `json:"extract_depth,omitempty"`
+
+	ApiKey string `json:"api_key"`
+}
+
+type TavilyCrawlResponse struct {
+	BaseURL      string                `json:"base_url"`
+	Results      []TavilyExtractResult `json:"results"`
+	ResponseTime float64               `json:"response_time"`
+}
+
+type TavilyMapResponse struct {
+	BaseURL      string   `json:"base_url"`
+	Results      []string `json:"results"`
+	ResponseTime float64  `json:"response_time"`
+}
+
+func (r *TavilyCrawlRequest) setApiKey(key string) {
+	r.ApiKey = key
+}
+
+// pageLimit return the most pages the request can process, the credits are reserved for them
+func (r *TavilyCrawlRequest) pageLimit() int {
+	if r.Limit <= 0 {
+		return defaultCrawlLimit
+	}
+	return r.Limit
+}
+
+// Crawl crawl the site from the url with options, the readable content of each page is returned
+func (c *Client) Crawl(ctx context.Context, rawURL string, h ...WithOptionHelper) (*TavilyCrawlResponse, error) {
+	tavilyReq, err := c.crawlRequest(rawURL, h, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := c.startSpan(ctx, "tavily.crawl", attribute.String("tavily.url", rawURL))
+	var tcResponse TavilyCrawlResponse
+	cost := requestCost{
+		estimate: CrawlCredits(tavilyReq.ExtractDepth, tavilyReq.pageLimit(), tavilyReq.Instructions != ""),
+		actual: func() int {
+			return CrawlCredits(tavilyReq.ExtractDepth, len(tcResponse.Results), tavilyReq.Instructions != "")
+		},
+	}
+	if err := c.post(ctx, TavilyCrawlEndpoint, cost, tavilyReq, &tcResponse); err != nil {
+		c.endCall(span, CallStats{Endpoint: TavilyCrawlEndpoint, Depth: tavilyReq.ExtractDepth, Err: err})
+		return nil, err
+	}
+	c.endCall(span, CallStats{Endpoint: TavilyCrawlEndpoint, Depth: tavilyReq.ExtractDepth, Results: len(tcResponse.Results), Credits: CrawlCredits(tavilyReq.ExtractDepth, len(tcResponse.Results), tavilyReq.Instructions != "")})
+	return &tcResponse, nil
+}
+
+// Map map the site from the url with options, only the urls of the pages are returned
+func (c *Client) Map(ctx context.Context, rawURL string, h ...WithOptionHelper) (*TavilyMapResponse, error) {
+	tavilyReq, err := c.crawlRequest(rawURL, h, false)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := c.startSpan(ctx, "tavily.map", attribute.String("tavily.url", rawURL))
+	var tmResponse TavilyMapResponse
+	cost := requestCost{
+		estimate: MapCredits(tavilyReq.pageLimit(), tavilyReq.Instructions != ""),
+		actual:   func() int { return MapCredits(len(tmResponse.Results), tavilyReq.Instructions != "") },
+	}
+	if err := c.post(ctx, TavilyMapEndpoint, cost, tavilyReq, &tmResponse); err != nil {
+		c.endCall(span, CallStats{Endpoint: TavilyMapEndpoint, Err: err})
+		return nil, err
+	}
+	c.endCall(span, CallStats{Endpoint: TavilyMapEndpoint, Results: len(tmResponse.Results), Credits: MapCredits(len(tmResponse.Results), tavilyReq.Instructions != "")})
+	return &tmResponse, nil
+}
+
+// crawlRequest build the request of crawl or map, the start url and the domains must pass the domain policy of the client
+func (c *Client) crawlRequest(rawURL string, h []WithOptionHelper, content bool) (*TavilyCrawlRequest, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("tavily crawl error: %s is not a valid url", rawURL)
+	}
+
+	tavilyParams, err := newOptionManager(h)
+	if err != nil {
+		return nil, err
+	}
+	tavilyReq, err := c.applyCrawlParams(*tavilyParams, content)
+	if err != nil {
+		return nil, err
+	}
+	tavilyReq.URL = rawURL
+
+	var include, exclude []string
+	if err := param.Assign(&include, tavilyParams.GetOptionWithDefault(OptionIncludeDomains, []string{})); err != nil {
+		return nil, err
+	}
+	if err := param.Assign(&exclude, tavilyParams.GetOptionWithDefault(OptionExcludeDomains, []string{})); err != nil {
+		return nil, err
+	}
+	include, exclude, err = mergeDomains(c.IncludeDomains, c.ExcludeDomains, include, exclude)
+	if err != nil {
+		return nil, err
+	}
+	host := strings.ToLower(u.Hostname())
+	if matchDomain(exclude, host) || (len(include) > 0 && !matchDomain(include, host)) {
+		return nil, fmt.Errorf("tavily crawl error: %s is not allowed by the domain policy", host)
+	}
+	tavilyReq.SelectDomains = domainPatterns(include)
+	tavilyReq.ExcludeDomains = domainPatterns(exclude)
+
+	return tavilyReq, nil
+}
+
+// applyCrawlParams
+// Available params, set by the typed options or WithOption:
+// - max_depth: int, WithMaxDepth
+// - max_breadth: int, WithMaxBreadth
+// - page_limit: int, WithPageLimit
+// - instructions: string, WithInstructions
+// - select_paths: []string, WithSelectPaths
+// - exclude_paths: []string, WithExcludePaths
+// - allow_external: bool, WithAllowExternal
+// - include_images: bool, WithIncludeImages, crawl only
+// - extract_depth: string, WithExtractDepth, crawl only
+func (c *Client) applyCrawlParams(options OptionManager, content bool) (*TavilyCrawlRequest, error) {
+
+	tavilyParams := TavilyCrawlRequest{}
+
+	if err := param.Assign(&tavilyParams.MaxDepth, options.GetOptionWithDefault(OptionMaxDepth, 0)); err != nil {
+		return nil, err
+	}
+	if err := param.Assign(&tavilyParams.MaxBreadth, options.GetOptionWithDefault(OptionMaxBreadth, 0)); err != nil {
+		return nil, err
+	}
+	if err := param.Assign(&tavilyParams.Limit, options.GetOptionWithDefault(OptionPageLimit, 0)); err != nil {
+		return nil, err
+	}
+	if err := param.Assign(&tavilyParams.Instructions, options.GetOptionWithDefault(OptionInstructions, "")); err != nil {
+		return nil, err
+	}
+	if err := param.Assign(&tavilyParams.SelectPaths, options.GetOptionWithDefault(OptionSelectPaths, []string{})); err != nil {
+		return nil, err
+	}
+	if err := param.Assign(&tavilyParams.ExcludePaths, options.GetOptionWithDefault(OptionExcludePaths, []string{})); err != nil {
+		return nil, err
+	}
+	if v, ok := options.GetOption(OptionAllowExternal); ok {
+		var allow bool
+		if err := param.Assign(&allow, v); err != nil {
+			return nil, err
+		}
+		tavilyParams.AllowExternal = &allow
+	}
+
+	if !content {
+		return &tavilyParams, nil
+	}
+	if err := param.Assign(&tavilyParams.IncludeImages, options.GetOptionWithDefault(OptionIncludeImages, false)); err != nil {
+		return nil, err
+	}
+	if err := param.Assign(&tavilyParams.ExtractDepth, options.GetOptionWithDefault(OptionExtractDepth, DepthBasic)); err != nil {
+		return nil, err
+	}
+
+	return &tavilyParams, nil
+}
+
+// domainPatterns turn the domains into the regular expressions matching them and their subdomains
+func domainPatterns(domains []string) []string {
+	if len(domains) == 0 {
+		return nil
+	}
+	patterns := make([]string, len(domains))
+	for i, domain := range domains {
+		patterns[i] = `^(.*\.)?` + regexp.QuoteMeta(domain) + `$`
+	}
+	return patterns
+}
@@ -0,0 +1,43 @@
+package tavily
+
+const (
+	// extractURLsPerCredit is the successful urls extracted by one credit, advanced extraction costs double
+	extractURLsPerCredit = 5
+	// mapPagesPerCredit is the pages mapped by one credit, mapping with instructions costs double
+	mapPagesPerCredit = 10
+)
+
+// SearchCredits return the estimated credits of a search, basic costs 1 and advanced costs 2
+func SearchCredits(depth string) int {
+	if depth == DepthAdvanced {
+		return 2
+	}
+	return 1
+}
+
+// ExtractCredits return the estimated credits of extracting the urls successfully
+func ExtractCredits(depth string, urls int) int {
+	credits := ceilDiv(urls, extractURLsPerCredit)
+	if depth == DepthAdvanced {
+		credits *= 2
+	}
+	return credits
+}
+
+// MapCredits return the estimated credits of mapping the pages
+func MapCredits(pages int, instructions bool) int {
+	credits := ceilDiv(pages, mapPagesPerCredit)
+	if instructions {
+		credits *= 2
+	}
+	return credits
+}
+
+// CrawlCredits return the estimated credits of crawling the pages, mapping them and extracting them
+func CrawlCredits(depth string, pages int, instructions bool) int {
+	return MapCredits(pages, instructions) + ExtractCredits(depth, pages)
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,58 @@
+package tavily
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mergeDomains merge the per-call domains with the domain policy of the client.
+// the excluded domains are the union of both lists, the per-call include list can only narrow
+// the policy one, and the included domains matching an excluded domain are dropped, so the policy exclude list always wins.
+func mergeDomains(policyInclude, policyExclude, include, exclude []string) ([]string, []string, error) {
+	policyInclude = normalizeDomains(policyInclude)
+	policyExclude = normalizeDomains(policyExclude)
+	include = normalizeDomains(include)
+	if len(include) == 0 && len(exclude) == 0 {
+		return policyInclude, policyExclude, nil
+	}
+
+	excluded := normalizeDomains(append(append([]string{}, policyExclude...), exclude...))
+
+	included := policyInclude
+	if len(include) > 0 {
+		included = nil
+		for _, domain := range include {
+			if len(policyInclude) == 0 || matchDomain(policyInclude, domain) {
+				included = append(included, domain)
+			}
+		}
+	}
+	requested := len(included) > 0 || len(include) > 0
+	included = dropDomains(included, excluded)
+	if requested && len(included) == 0 {
+		// an empty include list would search every domain
+		return nil, nil, fmt.Errorf("tavily include domains error: none of the include domains is allowed by the domain policy")
+	}
+	return included, excluded, nil
+}
+
+// dropDomains return the domains not matched by the excluded list
+func dropDomains(domains, excluded []string) []string {
+	var res []string
+	for _, domain := range domains {
+		if !matchDomain(excluded, domain) {
+			res = append(res, domain)
+		}
+	}
+	return res
+}
+
+// matchDomain report whether the domain is one of the list, or a subdomain of one of them
+func matchDomain(list []string, domain string) bool {
+	for _, item := range list {
+		if domain == item || strings.HasSuffix(domain, "."+item) {
+			return true
+		}
+	}
+	return false
+}
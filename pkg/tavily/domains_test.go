@@ -0,0 +1,111 @@
+package tavily
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMergeDomains(t *testing.T) {
+	tests := []struct {
+		name          string
+		policyInclude []string
+		policyExclude []string
+		include       []string
+		exclude       []string
+		wantInclude   []string
+		wantExclude   []string
+		wantErr       string
+	}{
+		{
+			name:          "policy only",
+			policyInclude: []string{" Go.dev ", "example.com", "go.dev"},
+			policyExclude: []string{"ads.example.com"},
+			wantInclude:   []string{"example.com", "go.dev"},
+			wantExclude:   []string{"ads.example.com"},
+		},
+		{
+			name:          "excluded domains are the union",
+			policyExclude: []string{"ads.example.com"},
+			exclude:       []string{"Spam.com", "ads.example.com"},
+			wantExclude:   []string{"ads.example.com", "spam.com"},
+		},
+		{
+			name:        "include without a policy",
+			include:     []string{"go.dev", "pkg.go.dev"},
+			wantInclude: []string{"go.dev", "pkg.go.dev"},
+		},
+		{
+			name:          "include narrows the policy",
+			policyInclude: []string{"example.com"},
+			include:       []string{"docs.example.com", "go.dev"},
+			wantInclude:   []string{"docs.example.com"},
+		},
+		{
+			name:          "policy exclude wins over include",
+			policyExclude: []string{"example.com"},
+			include:       []string{"docs.example.com", "go.dev"},
+			wantInclude:   []string{"go.dev"},
+			wantExclude:   []string{"example.com"},
+		},
+		{
+			name:          "exclude narrows the policy include",
+			policyInclude: []string{"example.com", "go.dev"},
+			exclude:       []string{"go.dev"},
+			wantInclude:   []string{"example.com"},
+			wantExclude:   []string{"go.dev"},
+		},
+		{
+			name:          "include outside the policy",
+			policyInclude: []string{"example.com"},
+			include:       []string{"go.dev"},
+			wantErr:       "none of the include domains is allowed",
+		},
+		{
+			name:          "include excluded by the policy",
+			policyExclude: []string{"go.dev"},
+			include:       []string{"pkg.go.dev"},
+			wantErr:       "none of the include domains is allowed",
+		},
+		{
+			name:          "policy include all excluded",
+			policyInclude: []string{"go.dev"},
+			exclude:       []string{"go.dev"},
+			wantErr:       "none of the include domains is allowed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			include, exclude, err := mergeDomains(tt.policyInclude, tt.policyExclude, tt.include, tt.exclude)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(include, tt.wantInclude) {
+				t.Errorf("got include %v, want %v", include, tt.wantInclude)
+			}
+			if !slices.Equal(exclude, tt.wantExclude) {
+				t.Errorf("got exclude %v, want %v", exclude, tt.wantExclude)
+			}
+		})
+	}
+}
+
+func TestMatchDomain(t *testing.T) {
+	list := []string{"example.com"}
+	for domain, want := range map[string]bool{
+		"example.com":      true,
+		"docs.example.com": true,
+		"badexample.com":   false,
+		"example.com.evil": false,
+	} {
+		if got := matchDomain(list, domain); got != want {
+			t.Errorf("matchDomain(%s) got %v, want %v", domain, got, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package tavily
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatusError is implemented by all the errors returned by the tavily api
+type StatusError interface {
+	error
+	Status() int
+}
+
+// APIError is the error returned by the tavily api with a non-200 status
+type APIError struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is the delay asked by the Retry-After header, zero if absent
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("tavily API error: status %d, %s", e.StatusCode, e.Message)
+}
+
+// Status return the http status of the response
+func (e *APIError) Status() int {
+	return e.StatusCode
+}
+
+// AuthError means the api key is missing, invalid or not allowed, status 401 and 403
+type AuthError struct{ APIError }
+
+// RateLimitError means the requests are sent too frequently, status 429
+type RateLimitError struct{ APIError }
+
+// QuotaError means the plan or pay-as-you-go credits of the key are exhausted, status 432 and 433
+type QuotaError struct{ APIError }
+
+// BadRequestError means the request params are rejected, status 400 and 422
+type BadRequestError struct{ APIError }
+
+// UpstreamError means tavily failed to handle the request, status 5xx
+type UpstreamError struct{ APIError }
+
+// newAPIError build the typed error by the response status
+func newAPIError(resp *http.Response, body []byte) error {
+	apiErr := APIError{
+		StatusCode: resp.StatusCode,
+		Message:    errorMessage(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return &AuthError{apiErr}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &RateLimitError{apiErr}
+	case resp.StatusCode == StatusPlanLimitExceeded || resp.StatusCode == StatusPayAsYouGoExceeded:
+		return &QuotaError{apiErr}
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity:
+		return &BadRequestError{apiErr}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return &UpstreamError{apiErr}
+	default:
+		return &apiErr
+	}
+}
+
+// errorMessage pick the error message from the tavily response body, like {"detail": {"error": "..."}}
+func errorMessage(body []byte) string {
+	var detail struct {
+		Detail json.RawMessage `json:"detail"`
+		Error  string          `json:"error"`
+	}
+	if err := json.Unmarshal(body, &detail); err == nil {
+		var nested struct {
+			Error string `json:"error"`
+		}
+		var text string
+		switch {
+		case json.Unmarshal(detail.Detail, &nested) == nil && nested.Error != "":
+			return nested.Error
+		case json.Unmarshal(detail.Detail, &text) == nil && text != "":
+			return text
+		case detail.Error != "":
+			return detail.Error
+		}
+	}
+
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		return "empty response body"
+	}
+	return msg
+}
+
+// parseRetryAfter parse the Retry-After header, in seconds or http date
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(v); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// statusOf return the http status carried by the error, 0 if there is none
+func statusOf(err error) int {
+	var statusErr StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status()
+	}
+	return 0
+}
+
+// retryAfterOf return the Retry-After delay carried by the error
+func retryAfterOf(err error) time.Duration {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter
+	}
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.RetryAfter
+	}
+	return 0
+}
+
+// isRetryable return true if the request is safe to send again, network failures, 429 and 5xx
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var rateLimitErr *RateLimitError
+	var upstreamErr *UpstreamError
+	var netErr *NetworkError
+	return errors.As(err, &rateLimitErr) || errors.As(err, &upstreamErr) || errors.As(err, &netErr)
+}
+
+// NetworkError means the request failed before a response is received
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("tavily API request error: %v", e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,8 @@
+package tavily
+
+import "time"
+
+// SetNow replace the clock of the budget
+func (b *Budget) SetNow(now func() time.Time) {
+	b.now = now
+}
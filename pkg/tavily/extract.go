@@ -0,0 +1,113 @@
+package tavily
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/param"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	TavilyExtractEndpoint = "/extract"
+	// MaxExtractURLs is the max number of urls tavily extract accepts in one request
+	MaxExtractURLs = 20
+)
+
+type TavilyExtractRequest struct {
+	Urls          []string `json:"urls"`
+	IncludeImages bool     `json:"include_images"`
+	ExtractDepth  string   `json:"extract_depth"`
+
+	ApiKey string `json:"api_key"`
+}
+
+type TavilyExtractResult struct {
+	URL        string   `json:"url"`
+	RawContent string   `json:"raw_content"`
+	Images     []string `json:"images"`
+}
+
+type TavilyExtractFailedResult struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+type TavilyExtractResponse struct {
+	Results       []TavilyExtractResult       `json:"results"`
+	FailedResults []TavilyExtractFailedResult `json:"failed_results"`
+	ResponseTime  float64                     `json:"response_time"`
+}
+
+// Extract extract the readable content of urls from tavily with options
+func (c *Client) Extract(ctx context.Context, urls []string, h ...WithOptionHelper) (*TavilyExtractResponse, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("tavily extract error: urls is required")
+	}
+	if len(urls) > MaxExtractURLs {
+		return nil, fmt.Errorf("tavily extract error: at most %d urls are allowed, got %d", MaxExtractURLs, len(urls))
+	}
+	excluded := normalizeDomains(c.ExcludeDomains)
+	for _, rawURL := range urls {
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Hostname() == "" {
+			return nil, fmt.Errorf("tavily extract error: %s is not a valid url", rawURL)
+		}
+		if host := strings.ToLower(u.Hostname()); matchDomain(excluded, host) {
+			return nil, fmt.Errorf("tavily extract error: %s is not allowed by the domain policy", host)
+		}
+	}
+
+	tavilyParams, err := newOptionManager(h)
+	if err != nil {
+		return nil, err
+	}
+
+	tavilyReq, err := c.applyExtractParams(*tavilyParams)
+	if err != nil {
+		return nil, err
+	}
+	tavilyReq.Urls = urls
+
+	ctx, span := c.startSpan(ctx, "tavily.extract", attribute.Int("tavily.urls", len(urls)))
+	var teResponse TavilyExtractResponse
+	cost := requestCost{
+		estimate: ExtractCredits(tavilyReq.ExtractDepth, len(urls)),
+		actual:   func() int { return ExtractCredits(tavilyReq.ExtractDepth, len(teResponse.Results)) },
+	}
+	if err := c.post(ctx, TavilyExtractEndpoint, cost, tavilyReq, &teResponse); err != nil {
+		c.endCall(span, CallStats{Endpoint: TavilyExtractEndpoint, Depth: tavilyReq.ExtractDepth, Err: err})
+		return nil, err
+	}
+	c.endCall(span, CallStats{Endpoint: TavilyExtractEndpoint, Depth: tavilyReq.ExtractDepth, Results: len(teResponse.Results), Credits: ExtractCredits(tavilyReq.ExtractDepth, len(teResponse.Results))})
+
+	return &teResponse, nil
+}
+
+func (r *TavilyExtractRequest) setApiKey(key string) {
+	r.ApiKey = key
+}
+
+// applyExtractParams
+// Available params, set by the typed options or WithOption:
+// - extract_depth: string, WithExtractDepth
+// - include_images: bool, WithIncludeImages
+func (c *Client) applyExtractParams(options OptionManager) (*TavilyExtractRequest, error) {
+
+	tavilyParams := TavilyExtractRequest{}
+
+	if err := param.Assign(&tavilyParams.ExtractDepth, options.GetOptionWithDefault(OptionExtractDepth, DepthBasic)); err != nil {
+		return nil, err
+	}
+	if tavilyParams.ExtractDepth != DepthBasic && tavilyParams.ExtractDepth != DepthAdvanced {
+		return nil, fmt.Errorf("tavily extract depth error: %s is not a valid extract depth", tavilyParams.ExtractDepth)
+	}
+
+	if err := param.Assign(&tavilyParams.IncludeImages, options.GetOptionWithDefault(OptionIncludeImages, false)); err != nil {
+		return nil, err
+	}
+
+	return &tavilyParams, nil
+}
@@ -0,0 +1,83 @@
+package tavily
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	DefaultTimeout     = 60 * time.Second
+	DefaultDialTimeout = 10 * time.Second
+)
+
+// HTTPOptions is the transport settings of the client sending requests to tavily
+type HTTPOptions struct {
+	// Timeout is the total timeout of one request, including reading the response body
+	Timeout time.Duration
+	// DialTimeout is the timeout of establishing the connection, and the tls handshake
+	DialTimeout time.Duration
+	// Proxy is the proxy url, empty means the proxy from HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+	Proxy string
+	// CACertFile is the pem file of extra ca certs to trust, like the corporate egress proxy ca
+	CACertFile string
+	// InsecureSkipVerify disables the tls certificate verification, for local fakes only
+	InsecureSkipVerify bool
+}
+
+// NewHTTPClient build the http client by the options
+func NewHTTPClient(opts HTTPOptions) (*http.Client, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = DefaultDialTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   opts.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = opts.DialTimeout
+
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("tavily http error: %s is not a valid proxy url", opts.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if opts.CACertFile != "" || opts.InsecureSkipVerify {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: opts.InsecureSkipVerify,
+		}
+		if opts.CACertFile != "" {
+			pem, err := os.ReadFile(opts.CACertFile)
+			if err != nil {
+				return nil, fmt.Errorf("tavily http error: failed to read ca cert: %v", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("tavily http error: no cert found in %s", opts.CACertFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
+	}, nil
+}
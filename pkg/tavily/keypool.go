@@ -0,0 +1,280 @@
+package tavily
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	KeyStrategyRoundRobin = "round-robin"
+	KeyStrategyLeastUsed  = "least-used"
+
+	DefaultKeyCooldown = 10 * time.Minute
+	// DefaultRateLimitCooldown is the bench of the key rate limited without Retry-After,
+	// rate limits pass soon, unlike the auth and quota errors benched for the cool-down
+	DefaultRateLimitCooldown = 5 * time.Second
+
+	// tavily status codes of the key which exceeds the plan or pay-as-you-go limit
+	StatusPlanLimitExceeded  = 432
+	StatusPayAsYouGoExceeded = 433
+)
+
+// KeyPool rotate the tavily api keys, key failed with auth or quota errors is benched for a cool-down period
+type KeyPool struct {
+	mu       sync.Mutex
+	keys     []*keyState
+	strategy string
+	cooldown time.Duration
+	next     int
+}
+
+type keyState struct {
+	key          string
+	requests     int64
+	failures     int64
+	lastStatus   int
+	lastUsed     time.Time
+	benchedUntil time.Time
+}
+
+// KeyUsage is the usage counters of a key, the key is masked
+type KeyUsage struct {
+	Key          string    `json:"key"`
+	Requests     int64     `json:"requests"`
+	Failures     int64     `json:"failures"`
+	LastStatus   int       `json:"last_status,omitempty"`
+	LastUsed     time.Time `json:"last_used,omitzero"`
+	BenchedUntil time.Time `json:"benched_until,omitzero"`
+	// Budget is the credits spent by the key, nil if there is no budget
+	Budget *BudgetUsage `json:"budget,omitempty"`
+}
+
+// NewKeyPool
+func NewKeyPool(keys []string, strategy string, cooldown time.Duration) (*KeyPool, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("tavily key pool error: at least one api key is required")
+	}
+	if strategy == "" {
+		strategy = KeyStrategyRoundRobin
+	}
+	if strategy != KeyStrategyRoundRobin && strategy != KeyStrategyLeastUsed {
+		return nil, fmt.Errorf("tavily key pool error: %s is not a valid strategy", strategy)
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultKeyCooldown
+	}
+
+	pool := &KeyPool{strategy: strategy, cooldown: cooldown}
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		pool.keys = append(pool.keys, &keyState{key: key})
+	}
+	if len(pool.keys) == 0 {
+		return nil, fmt.Errorf("tavily key pool error: at least one api key is required")
+	}
+	return pool, nil
+}
+
+// Len return the number of keys in the pool
+func (p *KeyPool) Len() int {
+	return len(p.keys)
+}
+
+// Acquire return the next available key by the strategy and count a request on it
+func (p *KeyPool) Acquire() (string, error) {
+	return p.acquire(nil)
+}
+
+// Keys return the keys of the pool
+func (p *KeyPool) Keys() []string {
+	keys := make([]string, len(p.keys))
+	for i, state := range p.keys {
+		keys[i] = state.key
+	}
+	return keys
+}
+
+// keysNotAllowedError means the keys not benched are all rejected by the allow func of acquire
+type keysNotAllowedError struct {
+	// keys is the rejected keys, the benched keys are not part of it
+	keys []string
+}
+
+func (e *keysNotAllowedError) Error() string {
+	return "tavily key pool error: no api key is allowed"
+}
+
+// available return the keys not benched
+func (p *KeyPool) available() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var keys []string
+	for _, state := range p.keys {
+		if !now.Before(state.benchedUntil) {
+			keys = append(keys, state.key)
+		}
+	}
+	return keys
+}
+
+// acquire return the next available key accepted by allow, nil allow accepts all the keys
+func (p *KeyPool) acquire(allow func(key string) bool) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var picked *keyState
+	var rejected []string
+	available := func(state *keyState) bool {
+		if now.Before(state.benchedUntil) {
+			return false
+		}
+		if allow != nil && !allow(state.key) {
+			rejected = append(rejected, state.key)
+			return false
+		}
+		return true
+	}
+	switch p.strategy {
+	case KeyStrategyLeastUsed:
+		for _, state := range p.keys {
+			if !available(state) {
+				continue
+			}
+			if picked == nil || state.requests < picked.requests {
+				picked = state
+			}
+		}
+	default:
+		for i := 0; i < len(p.keys); i++ {
+			state := p.keys[(p.next+i)%len(p.keys)]
+			if !available(state) {
+				continue
+			}
+			picked = state
+			p.next = (p.next + i + 1) % len(p.keys)
+			break
+		}
+	}
+
+	if picked == nil && len(rejected) > 0 {
+		return "", &keysNotAllowedError{keys: rejected}
+	}
+	if picked == nil {
+		err := &KeysCoolingDownError{Keys: len(p.keys)}
+		for _, state := range p.keys {
+			if d := state.benchedUntil.Sub(now); err.RetryAfter == 0 || d < err.RetryAfter {
+				err.RetryAfter = d
+			}
+		}
+		return "", err
+	}
+	picked.requests++
+	picked.lastUsed = now
+	return picked.key, nil
+}
+
+// Report record the response status of the key, the key is benched when the status is an auth, quota or rate limit error,
+// for retryAfter if given, otherwise for the cool-down period, or DefaultRateLimitCooldown for 429. it returns true if the key is benched
+func (p *KeyPool) Report(key string, status int, retryAfter time.Duration) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, state := range p.keys {
+		if state.key != key {
+			continue
+		}
+		state.lastStatus = status
+		if status != http.StatusOK {
+			state.failures++
+		}
+		if IsKeyStatus(status) {
+			cooldown := p.cooldown
+			if status == http.StatusTooManyRequests {
+				cooldown = min(DefaultRateLimitCooldown, p.cooldown)
+			}
+			if retryAfter > 0 {
+				cooldown = retryAfter
+			}
+			state.benchedUntil = time.Now().Add(cooldown)
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// NextAvailable return the time until the first benched key is available again, zero if any key is available
+func (p *KeyPool) NextAvailable() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for i, state := range p.keys {
+		d := state.benchedUntil.Sub(now)
+		if d <= 0 {
+			return 0
+		}
+		if i == 0 || d < wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// Usage return the usage counters of each key
+func (p *KeyPool) Usage() []KeyUsage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	usage := make([]KeyUsage, len(p.keys))
+	for i, state := range p.keys {
+		usage[i] = KeyUsage{
+			Key:        MaskKey(state.key),
+			Requests:   state.requests,
+			Failures:   state.failures,
+			LastStatus: state.lastStatus,
+			LastUsed:   state.lastUsed,
+		}
+		if time.Now().Before(state.benchedUntil) {
+			usage[i].BenchedUntil = state.benchedUntil
+		}
+	}
+	return usage
+}
+
+// KeysCoolingDownError means all the keys of the pool are benched
+type KeysCoolingDownError struct {
+	Keys int
+	// RetryAfter is the time until the first key is available again
+	RetryAfter time.Duration
+}
+
+func (e *KeysCoolingDownError) Error() string {
+	return fmt.Sprintf("tavily key pool error: all %d api keys are cooling down, the first one is available in %s", e.Keys, e.RetryAfter.Round(time.Second))
+}
+
+// IsKeyStatus return true if the status means the key itself can not be used for now
+func IsKeyStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, StatusPlanLimitExceeded, StatusPayAsYouGoExceeded:
+		return true
+	}
+	return false
+}
+
+// MaskKey keep only the prefix and the last 4 characters of the key
+func MaskKey(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:5] + "****" + key[len(key)-4:]
+}
@@ -0,0 +1,71 @@
+package tavily_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily/tavilytest"
+)
+
+func TestRateLimitedKeyIsRetried(t *testing.T) {
+	fake := tavilytest.NewServer()
+	defer fake.Close()
+	client := fake.Client()
+
+	// a 429 without Retry-After must not bench the only key for the cool-down
+	fake.FailNext(tavilytest.Fault{Status: http.StatusTooManyRequests})
+	if _, err := client.Search(context.Background(), "golang"); err != nil {
+		t.Fatalf("search after 429: %v", err)
+	}
+	if _, err := client.Search(context.Background(), "rust"); err != nil {
+		t.Fatalf("next search after 429: %v", err)
+	}
+	if got := len(fake.Requests()); got != 3 {
+		t.Errorf("got %d requests, want 3", got)
+	}
+}
+
+func TestKeyBenchedByStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		benched bool
+	}{
+		{http.StatusUnauthorized, true},
+		{http.StatusForbidden, true},
+		{tavily.StatusPlanLimitExceeded, true},
+		{tavily.StatusPayAsYouGoExceeded, true},
+		{http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			fake := tavilytest.NewServer()
+			defer fake.Close()
+			client := fake.Client("tvly-first-key-0001", "tvly-second-key-0002")
+
+			fake.FailNext(tavilytest.Fault{Status: tt.status})
+			_, err := client.Search(context.Background(), "golang")
+			if tt.benched {
+				if err != nil {
+					t.Fatalf("search with the second key: %v", err)
+				}
+			} else {
+				var badRequest *tavily.BadRequestError
+				if !errors.As(err, &badRequest) {
+					t.Fatalf("got error %v, want BadRequestError", err)
+				}
+			}
+
+			usage := client.KeyUsage()
+			if benched := !usage[0].BenchedUntil.IsZero(); benched != tt.benched {
+				t.Errorf("first key benched = %v, want %v", benched, tt.benched)
+			}
+			if !usage[1].BenchedUntil.IsZero() {
+				t.Error("second key is benched")
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package tavily
+
+import "time"
+
+// Metrics observe the traffic of the client, set it by WithMetrics
+type Metrics interface {
+	// ObserveRequest is called for each http request sent to tavily, retries included, status is 0 if no response is received
+	ObserveRequest(endpoint string, status int, latency time.Duration)
+	// ObserveCall is called for each finished search, extract, crawl or map call
+	ObserveCall(stats CallStats)
+}
+
+// CallStats is the stats of a search, extract, crawl or map call
+type CallStats struct {
+	Endpoint string
+	// Topic is the topic of the search, empty for the other endpoints
+	Topic string
+	// Depth is the search or extract depth
+	Depth string
+	// Results is the number of results returned
+	Results int
+	// CacheHit is whether the response is served from cache
+	CacheHit bool
+	// Shared is whether the response is shared with an identical search in flight
+	Shared bool
+	// Credits is the estimated credits spent, 0 for cache hits, shared responses and failed calls
+	Credits int
+	// Err is the error of the call
+	Err error
+}
+
+// WithMetrics set the metrics observing the requests and calls of the client
+func WithMetrics(metrics Metrics) ClientOption {
+	return func(c *Client) error {
+		c.metrics = metrics
+		return nil
+	}
+}
+
+// observeCall report the stats to the metrics if set
+func (c *Client) observeCall(stats CallStats) {
+	if c.metrics != nil {
+		c.metrics.ObserveCall(stats)
+	}
+}
+
+// observeRequest report the request to the metrics if set
+func (c *Client) observeRequest(endpoint string, status int, latency time.Duration) {
+	if c.metrics != nil {
+		c.metrics.ObserveRequest(endpoint, status, latency)
+	}
+}
@@ -0,0 +1,336 @@
+package tavily
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/param"
+)
+
+// option keys of the search, extract, crawl and map requests
+const (
+	OptionMaxResults       = "limit"
+	OptionTopic            = "topic"
+	OptionSearchDepth      = "search_depth"
+	OptionDays             = "days"
+	OptionIncludeImages    = "include_images"
+	OptionImageDescription = "include_image_descriptions"
+	OptionAnswer           = "include_answer"
+	OptionRawContent       = "include_raw_content"
+	OptionIncludeDomains   = "include_domains"
+	OptionExcludeDomains   = "exclude_domains"
+	OptionExtractDepth     = "extract_depth"
+	OptionMaxDepth         = "max_depth"
+	OptionMaxBreadth       = "max_breadth"
+	OptionPageLimit        = "page_limit"
+	OptionInstructions     = "instructions"
+	OptionSelectPaths      = "select_paths"
+	OptionExcludePaths     = "exclude_paths"
+	OptionAllowExternal    = "allow_external"
+
+	// MaxResultsLimit is the max results of a search allowed by tavily
+	MaxResultsLimit = 20
+	// MaxDays is the max days of a news search
+	MaxDays = 30
+)
+
+// OptionManager
+type OptionManager struct {
+	options map[string]any
+	err     error
+}
+
+// NewOptionManager
+func NewOptionManager() *OptionManager {
+	return &OptionManager{
+		options: make(map[string]any),
+	}
+}
+
+// GetOptionWithDefault
+func (o *OptionManager) GetOptionWithDefault(key string, defaultValue any) any {
+	if val, ok := o.options[key]; ok {
+		return val
+	}
+	return defaultValue
+}
+
+// GetOption
+func (o *OptionManager) GetOption(key string) (any, bool) {
+	v, ok := o.options[key]
+	return v, ok
+}
+
+// SetOption
+func (o *OptionManager) SetOption(key string, value any) {
+	if value == nil {
+		return
+	}
+	o.options[key] = value
+}
+
+// Err return the errors of the invalid options applied
+func (o *OptionManager) Err() error {
+	return o.err
+}
+
+// WithOption
+type WithOptionHelper func(*OptionManager)
+
+// newOptionManager apply the options, it returns the errors of the invalid options
+func newOptionManager(h []WithOptionHelper) (*OptionManager, error) {
+	options := NewOptionManager()
+	for _, helper := range h {
+		helper(options)
+	}
+	if err := options.Err(); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// withError is the option of an invalid value, the request fails with err when it is applied
+func withError(err error) WithOptionHelper {
+	return func(o *OptionManager) {
+		o.err = errors.Join(o.err, err)
+	}
+}
+
+func withValue(key string, value any) WithOptionHelper {
+	return func(o *OptionManager) {
+		o.SetOption(key, value)
+	}
+}
+
+// WithOption set the option by key, the value is converted and validated like the typed options,
+// a nil value is ignored, an unknown key fails the request.
+// prefer the typed options, like WithTopic and WithDays.
+func WithOption(key string, value any) WithOptionHelper {
+	if value == nil {
+		return func(*OptionManager) {}
+	}
+	switch key {
+	case OptionMaxResults:
+		return assignOption(key, value, WithMaxResults)
+	case OptionTopic:
+		return assignOption(key, value, WithTopic)
+	case OptionSearchDepth:
+		return assignOption(key, value, WithSearchDepth)
+	case OptionDays:
+		return assignOption(key, value, WithDays)
+	case OptionIncludeImages:
+		return assignOption(key, value, WithIncludeImages)
+	case OptionImageDescription:
+		return assignOption(key, value, WithImageDescriptions)
+	case OptionAnswer:
+		return assignOption(key, value, func(mode string) WithOptionHelper {
+			answerMode, err := parseAnswerMode(mode)
+			if err != nil {
+				return withError(err)
+			}
+			return WithAnswer(answerMode)
+		})
+	case OptionRawContent:
+		return assignOption(key, value, WithRawContent)
+	case OptionIncludeDomains:
+		return assignOption(key, value, WithIncludeDomains)
+	case OptionExcludeDomains:
+		return assignOption(key, value, WithExcludeDomains)
+	case OptionExtractDepth:
+		return assignOption(key, value, WithExtractDepth)
+	case OptionMaxDepth:
+		return assignOption(key, value, WithMaxDepth)
+	case OptionMaxBreadth:
+		return assignOption(key, value, WithMaxBreadth)
+	case OptionPageLimit:
+		return assignOption(key, value, WithPageLimit)
+	case OptionInstructions:
+		return assignOption(key, value, WithInstructions)
+	case OptionSelectPaths:
+		return assignOption(key, value, WithSelectPaths)
+	case OptionExcludePaths:
+		return assignOption(key, value, WithExcludePaths)
+	case OptionAllowExternal:
+		return assignOption(key, value, WithAllowExternal)
+	default:
+		return withError(fmt.Errorf("tavily option error: unknown option %s", key))
+	}
+}
+
+// assignOption convert the value of the legacy option, then build the typed option with it
+func assignOption[T any](key string, value any, build func(T) WithOptionHelper) WithOptionHelper {
+	var v T
+	if err := param.Assign(&v, value); err != nil {
+		return withError(fmt.Errorf("tavily %s error: %v", key, err))
+	}
+	return build(v)
+}
+
+// WithMaxResults set the max results of the search, between 1 and 20, default is 5
+func WithMaxResults(n int) WithOptionHelper {
+	if err := checkMaxResults(n); err != nil {
+		return withError(err)
+	}
+	return withValue(OptionMaxResults, n)
+}
+
+// WithTopic set the topic of the search, general or news, default is general
+func WithTopic(topic string) WithOptionHelper {
+	if err := checkTopic(topic); err != nil {
+		return withError(err)
+	}
+	return withValue(OptionTopic, topic)
+}
+
+// WithSearchDepth set the depth of the search, basic or advanced, default is basic
+func WithSearchDepth(depth string) WithOptionHelper {
+	if err := checkSearchDepth(depth); err != nil {
+		return withError(err)
+	}
+	return withValue(OptionSearchDepth, depth)
+}
+
+// WithDays set the days back of the news search, between 1 and 30, default is 7
+func WithDays(days int) WithOptionHelper {
+	if err := checkDays(days); err != nil {
+		return withError(err)
+	}
+	return withValue(OptionDays, days)
+}
+
+// WithIncludeImages set whether the response carries the images of the query
+func WithIncludeImages(include bool) WithOptionHelper {
+	return withValue(OptionIncludeImages, include)
+}
+
+// WithImageDescriptions set whether the images carry the descriptions
+func WithImageDescriptions(include bool) WithOptionHelper {
+	return withValue(OptionImageDescription, include)
+}
+
+// WithAnswer set the mode of the answer generated by tavily, AnswerNone disables it
+func WithAnswer(mode AnswerMode) WithOptionHelper {
+	if _, err := parseAnswerMode(string(mode)); err != nil {
+		return withError(err)
+	}
+	return withValue(OptionAnswer, string(mode))
+}
+
+// WithRawContent set whether the results carry the raw content of the pages
+func WithRawContent(include bool) WithOptionHelper {
+	return withValue(OptionRawContent, include)
+}
+
+// WithIncludeDomains set the domains the search is limited to, merged with the domain policy of the client
+func WithIncludeDomains(domains []string) WithOptionHelper {
+	return withValue(OptionIncludeDomains, normalizeDomains(domains))
+}
+
+// WithExcludeDomains set the domains excluded from the search, merged with the domain policy of the client
+func WithExcludeDomains(domains []string) WithOptionHelper {
+	return withValue(OptionExcludeDomains, normalizeDomains(domains))
+}
+
+// WithDomains set the included and excluded domains of the search
+func WithDomains(include, exclude []string) WithOptionHelper {
+	return func(o *OptionManager) {
+		WithIncludeDomains(include)(o)
+		WithExcludeDomains(exclude)(o)
+	}
+}
+
+// WithExtractDepth set the depth of the extraction, basic or advanced, default is basic
+func WithExtractDepth(depth string) WithOptionHelper {
+	if depth != DepthBasic && depth != DepthAdvanced {
+		return withError(fmt.Errorf("tavily extract depth error: %s is not a valid extract depth", depth))
+	}
+	return withValue(OptionExtractDepth, depth)
+}
+
+// WithMaxDepth set how many links deep the crawl or map follows from the url, between 1 and 5
+func WithMaxDepth(depth int) WithOptionHelper {
+	if depth < 1 || depth > MaxCrawlDepth {
+		return withError(fmt.Errorf("tavily max depth error: %d is not a valid max depth, max depth must between 1 and %d", depth, MaxCrawlDepth))
+	}
+	return withValue(OptionMaxDepth, depth)
+}
+
+// WithMaxBreadth set the max links followed on each page of the crawl or map
+func WithMaxBreadth(breadth int) WithOptionHelper {
+	if breadth < 1 {
+		return withError(fmt.Errorf("tavily max breadth error: %d is not a valid max breadth", breadth))
+	}
+	return withValue(OptionMaxBreadth, breadth)
+}
+
+// WithPageLimit set the max pages the crawl or map processes
+func WithPageLimit(limit int) WithOptionHelper {
+	if limit < 1 {
+		return withError(fmt.Errorf("tavily page limit error: %d is not a valid page limit", limit))
+	}
+	return withValue(OptionPageLimit, limit)
+}
+
+// WithInstructions set the natural language instructions guiding the crawl or map
+func WithInstructions(instructions string) WithOptionHelper {
+	return withValue(OptionInstructions, instructions)
+}
+
+// WithSelectPaths set the regular expressions of the paths the crawl or map is limited to, like /docs/.*
+func WithSelectPaths(paths []string) WithOptionHelper {
+	if err := checkPatterns(paths); err != nil {
+		return withError(err)
+	}
+	return withValue(OptionSelectPaths, paths)
+}
+
+// WithExcludePaths set the regular expressions of the paths excluded from the crawl or map
+func WithExcludePaths(paths []string) WithOptionHelper {
+	if err := checkPatterns(paths); err != nil {
+		return withError(err)
+	}
+	return withValue(OptionExcludePaths, paths)
+}
+
+// WithAllowExternal set whether the crawl or map follows the links to external domains
+func WithAllowExternal(allow bool) WithOptionHelper {
+	return withValue(OptionAllowExternal, allow)
+}
+
+func checkMaxResults(n int) error {
+	if n < 1 || n > MaxResultsLimit {
+		return fmt.Errorf("tavily limit error: %d is not a valid limit, limit must between 1 and %d", n, MaxResultsLimit)
+	}
+	return nil
+}
+
+func checkTopic(topic string) error {
+	if topic != TopicGeneral && topic != TopicNews {
+		return fmt.Errorf("tavily topic error: %s is not a valid topic", topic)
+	}
+	return nil
+}
+
+func checkSearchDepth(depth string) error {
+	if depth != DepthBasic && depth != DepthAdvanced {
+		return fmt.Errorf("tavily search depth error: %s is not a valid search depth", depth)
+	}
+	return nil
+}
+
+func checkDays(days int) error {
+	if days < 1 || days > MaxDays {
+		return fmt.Errorf("tavily days error: %d is not a valid days, days must between 1 and %d", days, MaxDays)
+	}
+	return nil
+}
+
+func checkPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("tavily path error: %s is not a valid regular expression", pattern)
+		}
+	}
+	return nil
+}
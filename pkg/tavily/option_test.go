@@ -0,0 +1,104 @@
+package tavily_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+func apply(h ...tavily.WithOptionHelper) *tavily.OptionManager {
+	o := tavily.NewOptionManager()
+	for _, helper := range h {
+		helper(o)
+	}
+	return o
+}
+
+func TestWithOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   any
+		want    any
+		wantErr string
+	}{
+		{name: "limit", key: tavily.OptionMaxResults, value: 7, want: 7},
+		{name: "limit from json number", key: tavily.OptionMaxResults, value: float64(7), want: 7},
+		{name: "limit from string", key: tavily.OptionMaxResults, value: "7", want: 7},
+		{name: "limit too large", key: tavily.OptionMaxResults, value: tavily.MaxResultsLimit + 1, wantErr: "limit must between 1 and 20"},
+		{name: "limit zero", key: tavily.OptionMaxResults, value: 0, wantErr: "not a valid limit"},
+		{name: "limit not a number", key: tavily.OptionMaxResults, value: "many", wantErr: "tavily limit error"},
+		{name: "topic", key: tavily.OptionTopic, value: tavily.TopicNews, want: tavily.TopicNews},
+		{name: "bad topic", key: tavily.OptionTopic, value: "sports", wantErr: "not a valid topic"},
+		{name: "search depth", key: tavily.OptionSearchDepth, value: tavily.DepthAdvanced, want: tavily.DepthAdvanced},
+		{name: "bad search depth", key: tavily.OptionSearchDepth, value: "deep", wantErr: "not a valid search depth"},
+		{name: "days", key: tavily.OptionDays, value: 30, want: 30},
+		{name: "days too large", key: tavily.OptionDays, value: 31, wantErr: "days must between 1 and 30"},
+		{name: "answer", key: tavily.OptionAnswer, value: "advanced", want: "advanced"},
+		{name: "bad answer", key: tavily.OptionAnswer, value: "long", wantErr: "answer"},
+		{name: "raw content", key: tavily.OptionRawContent, value: true, want: true},
+		{name: "extract depth", key: tavily.OptionExtractDepth, value: tavily.DepthBasic, want: tavily.DepthBasic},
+		{name: "bad extract depth", key: tavily.OptionExtractDepth, value: "full", wantErr: "not a valid extract depth"},
+		{name: "max depth too large", key: tavily.OptionMaxDepth, value: tavily.MaxCrawlDepth + 1, wantErr: "not a valid max depth"},
+		{name: "bad max breadth", key: tavily.OptionMaxBreadth, value: 0, wantErr: "not a valid max breadth"},
+		{name: "bad page limit", key: tavily.OptionPageLimit, value: -1, wantErr: "not a valid page limit"},
+		{name: "bad select paths", key: tavily.OptionSelectPaths, value: []string{"/docs/("}, wantErr: "not a valid regular expression"},
+		{name: "unknown option", key: "country", value: "us", wantErr: "unknown option country"},
+		{name: "nil value", key: "country", value: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := apply(tavily.WithOption(tt.key, tt.value))
+			if tt.wantErr != "" {
+				if err := o.Err(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				if _, ok := o.GetOption(tt.key); ok {
+					t.Errorf("invalid option %s is set", tt.key)
+				}
+				return
+			}
+			if err := o.Err(); err != nil {
+				t.Fatal(err)
+			}
+			got, ok := o.GetOption(tt.key)
+			if tt.want == nil {
+				if ok {
+					t.Errorf("got option %v, want it unset", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("got %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptionErrorsJoined(t *testing.T) {
+	o := apply(
+		tavily.WithOption(tavily.OptionTopic, "sports"),
+		tavily.WithOption(tavily.OptionDays, 0),
+		tavily.WithOption(tavily.OptionMaxResults, 3),
+	)
+	err := o.Err()
+	if err == nil || !strings.Contains(err.Error(), "topic") || !strings.Contains(err.Error(), "days") {
+		t.Fatalf("got error %v, want the topic and days errors", err)
+	}
+	if got, _ := o.GetOption(tavily.OptionMaxResults); got != 3 {
+		t.Errorf("got limit %v, want 3", got)
+	}
+}
+
+func TestWithDomains(t *testing.T) {
+	o := apply(tavily.WithOption(tavily.OptionIncludeDomains, []any{" Go.dev", "example.com", "go.dev"}))
+	if err := o.Err(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := o.GetOption(tavily.OptionIncludeDomains)
+	if domains, ok := got.([]string); !ok || !slices.Equal(domains, []string{"example.com", "go.dev"}) {
+		t.Errorf("got include domains %v, want them normalized", got)
+	}
+}
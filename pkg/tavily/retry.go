@@ -0,0 +1,43 @@
+package tavily
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	DefaultMaxRetries     = 3
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+	DefaultRetryMaxDelay  = 30 * time.Second
+)
+
+// backoff return the delay before the retry, Retry-After takes precedence,
+// otherwise it is the exponential delay with jitter in [d/2, d)
+func (c *Client) backoff(retries int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	d := c.RetryBaseDelay << retries
+	if d <= 0 || d > c.RetryMaxDelay {
+		d = c.RetryMaxDelay
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
+
+// sleep wait for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
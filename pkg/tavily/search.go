@@ -0,0 +1,236 @@
+package tavily
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/param"
+)
+
+const (
+	TopicGeneral         = "general"
+	TopicNews            = "news"
+	DepthBasic           = "basic"
+	DepthAdvanced        = "advanced"
+	DefaultDays          = 7
+	DefaultBaseURL       = "https://api.tavily.com"
+	TavilySearchEndpoint = "/search"
+)
+
+type TavilySearchResquest struct {
+	MaxResults int `json:"max_results"`
+
+	IncludeImages     bool       `json:"include_images"`
+	IncludeImageDesc  bool       `json:"include_image_descriptions"`
+	IncludeAnswer     AnswerMode `json:"include_answer"`
+	IncludeRawContent bool       `json:"include_raw_content"`
+	Query             string     `json:"query"`
+
+	ApiKey      string `json:"api_key"`
+	Topic       string `json:"topic"`
+	SearchDepth string `json:"search_depth"`
+	Days        int    `json:"days"`
+
+	IncludeDomains []string `json:"include_domains,omitempty"`
+	ExcludeDomains []string `json:"exclude_domains,omitempty"`
+}
+
+type TavilySearchImage struct {
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+type TavilySearchResult struct {
+	Title         string  `json:"title"`
+	URL           string  `json:"url"`
+	Content       string  `json:"content"`
+	Score         float64 `json:"score"`
+	RawContent    *string `json:"raw_content"`
+	PublishedDate *string `json:"published_date"`
+}
+
+type TavilySearchResponse struct {
+	Query             string               `json:"query"`
+	FollowUpQuestions *string              `json:"follow_up_questions"`
+	Answer            *string              `json:"answer"`
+	Images            []TavilySearchImage  `json:"images"`
+	Results           []TavilySearchResult `json:"results"`
+	ResponseTime      float64              `json:"response_time"`
+
+	// CacheHit is true when the response is served from cache
+	CacheHit bool `json:"-"`
+	// Downgraded is true when the advanced search is downgraded to basic by the credit budget
+	Downgraded bool `json:"-"`
+	// Shared is true when the response is shared with an identical search in flight, it spends no credits
+	Shared bool `json:"-"`
+}
+
+// Search search from tavily with keyword and options
+func (c *Client) Search(ctx context.Context, query string, h ...WithOptionHelper) (*TavilySearchResponse, error) {
+
+	tavilyParams, err := newOptionManager(h)
+	if err != nil {
+		return nil, err
+	}
+
+	tavilyReq, err := c.applyParams(*tavilyParams)
+	if err != nil {
+		return nil, err
+	}
+	tavilyReq.Query = query
+	tavilyReq.IncludeDomains, tavilyReq.ExcludeDomains, err = mergeDomains(c.IncludeDomains, c.ExcludeDomains, tavilyReq.IncludeDomains, tavilyReq.ExcludeDomains)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := c.startSpan(ctx, "tavily.search", attribute.String("tavily.query", query))
+	var key string
+	ttl := c.cacheTTL.Get(tavilyReq.Topic)
+	if c.cache != nil && ttl > 0 {
+		key = cacheKey(*tavilyReq)
+		if cached, ok := c.cache.Get(key); ok {
+			var tsResponse TavilySearchResponse
+			if err := json.Unmarshal(cached, &tsResponse); err == nil {
+				tsResponse.CacheHit = true
+				c.endCall(span, CallStats{Endpoint: TavilySearchEndpoint, Topic: tavilyReq.Topic, Depth: tavilyReq.SearchDepth, Results: len(tsResponse.Results), CacheHit: true})
+				return &tsResponse, nil
+			}
+		}
+	}
+
+	downgraded := c.downgrade(ctx, tavilyReq)
+	if downgraded {
+		// the basic response must not be served to the advanced searches from cache
+		key = ""
+	}
+
+	// identical searches in flight are sent once, the others share the response
+	tsResponse, shared, err := c.flights.do(ctx, cacheKey(*tavilyReq), func(ctx context.Context) (*TavilySearchResponse, error) {
+		var tsResponse TavilySearchResponse
+		if err := c.post(ctx, TavilySearchEndpoint, requestCost{estimate: SearchCredits(tavilyReq.SearchDepth)}, tavilyReq, &tsResponse); err != nil {
+			return nil, err
+		}
+		if key != "" {
+			if b, err := json.Marshal(tsResponse); err == nil {
+				c.cache.Set(key, b, ttl)
+			}
+		}
+		return &tsResponse, nil
+	})
+	stats := CallStats{Endpoint: TavilySearchEndpoint, Topic: tavilyReq.Topic, Depth: tavilyReq.SearchDepth, Shared: shared}
+	if err != nil {
+		stats.Err = err
+		c.endCall(span, stats)
+		return nil, err
+	}
+	stats.Results = len(tsResponse.Results)
+	if !shared {
+		stats.Credits = SearchCredits(tavilyReq.SearchDepth)
+	}
+	c.endCall(span, stats)
+	tsResponse.Downgraded = downgraded
+	tsResponse.Shared = shared
+
+	// 整理返回结果
+	return tsResponse, nil
+}
+
+// downgrade change the advanced search to basic when the budget policy is downgrade,
+// and no key out of the cool-down has the budget of an advanced search but some has the budget of a basic one
+func (c *Client) downgrade(ctx context.Context, req *TavilySearchResquest) bool {
+	if c.budget == nil || c.budget.Policy() != BudgetDowngrade || req.SearchDepth != DepthAdvanced {
+		return false
+	}
+	keys := c.Keys.available()
+	if c.budget.AllowsAny(keys, SearchCredits(DepthAdvanced)) || !c.budget.AllowsAny(keys, SearchCredits(DepthBasic)) {
+		return false
+	}
+	c.logger.InfoContext(ctx, "tavily search downgraded to basic by the credit budget")
+	req.SearchDepth = DepthBasic
+	return true
+}
+
+// SearchAnswer search from tavily with keyword and options, the response carries the answer generated by tavily,
+// mode is basic or advanced
+func (c *Client) SearchAnswer(ctx context.Context, query string, mode AnswerMode, h ...WithOptionHelper) (*TavilySearchResponse, error) {
+	h = append(h, WithAnswer(mode))
+	return c.Search(ctx, query, h...)
+}
+
+// SearchImage search text and image from tavily with keyword and options
+func (c *Client) SearchImage(ctx context.Context, query string, h ...WithOptionHelper) (*TavilySearchResponse, error) {
+	h = append(h, WithIncludeImages(true), WithImageDescriptions(true))
+	return c.Search(ctx, query, h...)
+}
+
+// applyParams
+// Available params, set by the typed options or WithOption:
+// - limit: int, WithMaxResults
+// - topic: string, WithTopic
+// - search_depth: string, WithSearchDepth
+// - days: int, WithDays
+// - include_images: bool, WithIncludeImages
+// - include_image_descriptions: bool, WithImageDescriptions
+// - include_answer: AnswerMode, WithAnswer
+// - include_raw_content: bool, WithRawContent
+// - include_domains: []string, WithIncludeDomains, merged with the domain policy of the client
+// - exclude_domains: []string, WithExcludeDomains, merged with the domain policy of the client
+func (c *Client) applyParams(options OptionManager) (*TavilySearchResquest, error) {
+
+	tavilyParams := TavilySearchResquest{}
+
+	if err := param.Assign(&tavilyParams.MaxResults, options.GetOptionWithDefault(OptionMaxResults, 5)); err != nil {
+		return nil, err
+	}
+	if err := checkMaxResults(tavilyParams.MaxResults); err != nil {
+		return nil, err
+	}
+
+	if err := param.Assign(&tavilyParams.Topic, options.GetOptionWithDefault(OptionTopic, TopicGeneral)); err != nil {
+		return nil, err
+	}
+	if err := checkTopic(tavilyParams.Topic); err != nil {
+		return nil, err
+	}
+
+	if err := param.Assign(&tavilyParams.SearchDepth, options.GetOptionWithDefault(OptionSearchDepth, DepthBasic)); err != nil {
+		return nil, err
+	}
+	if err := checkSearchDepth(tavilyParams.SearchDepth); err != nil {
+		return nil, err
+	}
+
+	if err := param.Assign(&tavilyParams.Days, options.GetOptionWithDefault(OptionDays, DefaultDays)); err != nil {
+		return nil, err
+	}
+	if err := checkDays(tavilyParams.Days); err != nil {
+		return nil, err
+	}
+
+	if err := param.Assign(&tavilyParams.IncludeImages, options.GetOptionWithDefault(OptionIncludeImages, false)); err != nil {
+		return nil, err
+	}
+	if err := param.Assign(&tavilyParams.IncludeImageDesc, options.GetOptionWithDefault(OptionImageDescription, false)); err != nil {
+		return nil, err
+	}
+	if err := param.Assign(&tavilyParams.IncludeAnswer, options.GetOptionWithDefault(OptionAnswer, string(AnswerNone))); err != nil {
+		return nil, err
+	}
+	if err := param.Assign(&tavilyParams.IncludeRawContent, options.GetOptionWithDefault(OptionRawContent, false)); err != nil {
+		return nil, err
+	}
+	if err := param.Assign(&tavilyParams.IncludeDomains, options.GetOptionWithDefault(OptionIncludeDomains, []string{})); err != nil {
+		return nil, err
+	}
+	if err := param.Assign(&tavilyParams.ExcludeDomains, options.GetOptionWithDefault(OptionExcludeDomains, []string{})); err != nil {
+		return nil, err
+	}
+
+	return &tavilyParams, nil
+}
+
+func (r *TavilySearchResquest) setApiKey(key string) {
+	r.ApiKey = key
+}
@@ -0,0 +1,95 @@
+package tavilytest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+// FixtureResults is the number of results in the search fixture
+const FixtureResults = 10
+
+// FixtureImages is the number of images in the search fixture
+const FixtureImages = 3
+
+// SearchFixture return the search response of the query, results are ranked by score,
+// imageURL builds the url of the n-th image
+func SearchFixture(query string, imageURL func(n int) string) *tavily.TavilySearchResponse {
+	res := &tavily.TavilySearchResponse{
+		Query:        query,
+		Answer:       ptr(fmt.Sprintf("%s is a fixture answer generated by the fake tavily api.", query)),
+		ResponseTime: 0.42,
+	}
+
+	for i := 1; i <= FixtureResults; i++ {
+		res.Results = append(res.Results, tavily.TavilySearchResult{
+			Title:         fmt.Sprintf("%s result %d", query, i),
+			URL:           fmt.Sprintf("https://example.com/%s/%d", slug(query), i),
+			Content:       fmt.Sprintf("Snippet %d about %s.", i, query),
+			Score:         1 - float64(i)/100,
+			RawContent:    ptr(fmt.Sprintf("# %s result %d\n\nFull page %d about %s.\n\nMore details about %s in the second paragraph.", query, i, i, query, query)),
+			PublishedDate: ptr("Mon, 02 Jan 2006 15:04:05 GMT"),
+		})
+	}
+
+	for i := 1; i <= FixtureImages; i++ {
+		res.Images = append(res.Images, tavily.TavilySearchImage{
+			URL:         imageURL(i),
+			Description: fmt.Sprintf("Image %d of %s.", i, query),
+		})
+	}
+	return res
+}
+
+// ExtractFixture return the extract response of the urls, urls not starting with http are failed
+func ExtractFixture(urls []string) *tavily.TavilyExtractResponse {
+	res := &tavily.TavilyExtractResponse{ResponseTime: 0.21}
+	for _, u := range urls {
+		if !strings.HasPrefix(u, "http") {
+			res.FailedResults = append(res.FailedResults, tavily.TavilyExtractFailedResult{
+				URL:   u,
+				Error: "invalid url",
+			})
+			continue
+		}
+		res.Results = append(res.Results, tavily.TavilyExtractResult{
+			URL:        u,
+			RawContent: fmt.Sprintf("Readable content of %s.", u),
+			Images:     []string{u + "/cover.png"},
+		})
+	}
+	return res
+}
+
+// FixturePages is the number of pages in the crawl and map fixtures, before the limit is applied
+const FixturePages = 5
+
+// CrawlFixture return the crawl response of the site, with the pages under /docs of the url
+func CrawlFixture(baseURL string) *tavily.TavilyCrawlResponse {
+	res := &tavily.TavilyCrawlResponse{BaseURL: baseURL, ResponseTime: 1.3}
+	for _, u := range MapFixture(baseURL).Results {
+		res.Results = append(res.Results, tavily.TavilyExtractResult{
+			URL:        u,
+			RawContent: fmt.Sprintf("Readable content of %s.", u),
+		})
+	}
+	return res
+}
+
+// MapFixture return the map response of the site, with the pages under /docs of the url
+func MapFixture(baseURL string) *tavily.TavilyMapResponse {
+	res := &tavily.TavilyMapResponse{BaseURL: baseURL, ResponseTime: 0.8}
+	for i := 1; i <= FixturePages; i++ {
+		res.Results = append(res.Results, fmt.Sprintf("%s/docs/%d", strings.TrimRight(baseURL, "/"), i))
+	}
+	return res
+}
+
+func slug(s string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), " ", "-")
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
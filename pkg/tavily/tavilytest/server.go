@@ -0,0 +1,325 @@
+// Package tavilytest provides a fake tavily api for tests, it serves the search, extract, crawl and map
+// fixtures over httptest, and can inject errors and latency.
+package tavilytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/y7ut/mcp-tavily-search/pkg/tavily"
+)
+
+// InvalidKey is the api key the fake always rejects with 401
+const InvalidKey = "tvly-invalid"
+
+// Fault is the error response the fake returns instead of the fixture
+type Fault struct {
+	Status int
+	// Body is the response body, default is a tavily style {"detail": {"error": "..."}}
+	Body string
+	// Header is the extra response header, like Retry-After
+	Header http.Header
+}
+
+// Request is the request received by the fake
+type Request struct {
+	Path string
+	// Body is the decoded json body
+	Body map[string]any
+	// Header is the request header
+	Header http.Header
+}
+
+// Server is a fake tavily api
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	latency  time.Duration
+	faults   []Fault
+	requests []Request
+
+	search  func(query string) *tavily.TavilySearchResponse
+	extract func(urls []string) *tavily.TavilyExtractResponse
+	crawl   func(url string) *tavily.TavilyCrawlResponse
+}
+
+// NewServer start a fake tavily api with the default fixtures, close it when done
+func NewServer() *Server {
+	s := &Server{}
+	s.search = s.defaultSearch
+	s.extract = s.defaultExtract
+	s.crawl = CrawlFixture
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(tavily.TavilySearchEndpoint, s.handleSearch)
+	mux.HandleFunc(tavily.TavilyExtractEndpoint, s.handleExtract)
+	mux.HandleFunc(tavily.TavilyCrawlEndpoint, s.handleCrawl)
+	mux.HandleFunc(tavily.TavilyMapEndpoint, s.handleCrawl)
+	mux.HandleFunc("/images/", s.handleImage)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Client return a tavily client sending requests to the fake, retry delays are shortened
+func (s *Server) Client(keys ...string) *tavily.Client {
+	if len(keys) == 0 {
+		keys = []string{"tvly-test-key"}
+	}
+	pool, err := tavily.NewKeyPool(keys, tavily.KeyStrategyRoundRobin, 0)
+	if err != nil {
+		panic(err)
+	}
+	client, err := tavily.NewClient(
+		tavily.WithKeyPool(pool),
+		tavily.WithBaseURL(s.URL),
+		tavily.WithHTTPClient(s.Server.Client()),
+		tavily.WithRetry(tavily.DefaultMaxRetries, time.Millisecond, 100*time.Millisecond),
+	)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// SetLatency delay every api response by d
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// FailNext queue the faults, each following api request consumes one of them before the fixtures are served
+func (s *Server) FailNext(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, faults...)
+}
+
+// SetSearch replace the search fixture
+func (s *Server) SetSearch(fn func(query string) *tavily.TavilySearchResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.search = fn
+}
+
+// SetExtract replace the extract fixture
+func (s *Server) SetExtract(fn func(urls []string) *tavily.TavilyExtractResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.extract = fn
+}
+
+// SetCrawl replace the crawl fixture, the map response is the urls of the crawled pages
+func (s *Server) SetCrawl(fn func(url string) *tavily.TavilyCrawlResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crawl = fn
+}
+
+// Requests return the api requests received, image downloads are not recorded
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// ImageURL return the url of the fake image n
+func (s *Server) ImageURL(n int) string {
+	return fmt.Sprintf("%s/images/%d.png", s.URL, n)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.receive(w, r)
+	if !ok {
+		return
+	}
+	query, _ := body["query"].(string)
+
+	s.mu.Lock()
+	search := s.search
+	s.mu.Unlock()
+
+	res := search(query)
+	if limit, ok := body["max_results"].(float64); ok && int(limit) < len(res.Results) {
+		res.Results = res.Results[:int(limit)]
+	}
+	if include, _ := body["include_images"].(bool); !include {
+		res.Images = nil
+	}
+	switch mode := body["include_answer"].(type) {
+	case string:
+		if mode == string(tavily.AnswerAdvanced) && res.Answer != nil {
+			res.Answer = ptr(*res.Answer + " The advanced answer has more details.")
+		}
+	case bool:
+		if !mode {
+			res.Answer = nil
+		}
+	default:
+		res.Answer = nil
+	}
+	if include, _ := body["include_raw_content"].(bool); !include {
+		for i := range res.Results {
+			res.Results[i].RawContent = nil
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) handleExtract(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.receive(w, r)
+	if !ok {
+		return
+	}
+	var urls []string
+	if items, ok := body["urls"].([]any); ok {
+		for _, item := range items {
+			if u, ok := item.(string); ok {
+				urls = append(urls, u)
+			}
+		}
+	}
+
+	s.mu.Lock()
+	extract := s.extract
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, extract(urls))
+}
+
+// handleCrawl serve both crawl and map, the pages are cut by limit
+func (s *Server) handleCrawl(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.receive(w, r)
+	if !ok {
+		return
+	}
+	u, _ := body["url"].(string)
+
+	s.mu.Lock()
+	crawl := s.crawl
+	s.mu.Unlock()
+
+	res := crawl(u)
+	if limit, ok := body["limit"].(float64); ok && int(limit) < len(res.Results) {
+		res.Results = res.Results[:int(limit)]
+	}
+	if r.URL.Path == tavily.TavilyMapEndpoint {
+		m := &tavily.TavilyMapResponse{BaseURL: res.BaseURL, ResponseTime: res.ResponseTime}
+		for _, page := range res.Results {
+			m.Results = append(m.Results, page.URL)
+		}
+		writeJSON(w, http.StatusOK, m)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// receive record the request, apply the latency, the api key check and the queued fault,
+// it returns false if the response is already written
+func (s *Server) receive(w http.ResponseWriter, r *http.Request) (map[string]any, bool) {
+	if r.Method != http.MethodPost {
+		writeError(w, Fault{Status: http.StatusMethodNotAllowed})
+		return nil, false
+	}
+
+	var body map[string]any
+	raw, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(raw, &body); err != nil {
+		writeError(w, Fault{Status: http.StatusBadRequest, Body: fmt.Sprintf(`{"detail":{"error":"invalid json: %v"}}`, err)})
+		return nil, false
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Path: r.URL.Path, Body: body, Header: r.Header.Clone()})
+	latency := s.latency
+	var fault *Fault
+	if len(s.faults) > 0 {
+		fault = &s.faults[0]
+		s.faults = s.faults[1:]
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return nil, false
+		}
+	}
+
+	if fault != nil {
+		writeError(w, *fault)
+		return nil, false
+	}
+
+	if key, _ := body["api_key"].(string); key == "" || key == InvalidKey {
+		writeError(w, Fault{Status: http.StatusUnauthorized, Body: `{"detail":{"error":"Unauthorized: missing or invalid API key."}}`})
+		return nil, false
+	}
+	return body, true
+}
+
+// handleImage serve a small png, the color is picked by the image number
+func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
+	var n int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/images/"), "%d.png", &n); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(n * 40), G: 128, B: 255 - uint8(n*40), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
+
+func (s *Server) defaultSearch(query string) *tavily.TavilySearchResponse {
+	return SearchFixture(query, s.ImageURL)
+}
+
+func (s *Server) defaultExtract(urls []string) *tavily.TavilyExtractResponse {
+	return ExtractFixture(urls)
+}
+
+func writeError(w http.ResponseWriter, fault Fault) {
+	for k, v := range fault.Header {
+		w.Header()[k] = v
+	}
+	body := fault.Body
+	if body == "" {
+		text := http.StatusText(fault.Status)
+		if text == "" {
+			text = fmt.Sprintf("status %d", fault.Status)
+		}
+		body = fmt.Sprintf(`{"detail":{"error":"%s"}}`, text)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(fault.Status)
+	w.Write([]byte(body))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,56 @@
+package tavily
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of the client
+const tracerName = "github.com/y7ut/mcp-tavily-search/pkg/tavily"
+
+// WithTracerProvider set the provider of the spans of the calls,
+// without it the spans are created by the provider of the span in the context, if any
+func WithTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(c *Client) error {
+		c.tracerProvider = tp
+		return nil
+	}
+}
+
+// startSpan start the span of a search, extract, crawl or map call, end it by endCall
+func (c *Client) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	tp := c.tracerProvider
+	if tp == nil {
+		tp = trace.SpanFromContext(ctx).TracerProvider()
+	}
+	return tp.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endCall report the stats of the call to the metrics and the span, then end the span
+func (c *Client) endCall(span trace.Span, stats CallStats) {
+	c.observeCall(stats)
+
+	span.SetAttributes(
+		attribute.String("tavily.endpoint", stats.Endpoint),
+		attribute.Int("tavily.results", stats.Results),
+		attribute.Bool("tavily.cache_hit", stats.CacheHit),
+		attribute.Bool("tavily.shared", stats.Shared),
+		attribute.Int("tavily.credits", stats.Credits),
+	)
+	if stats.Topic != "" {
+		span.SetAttributes(attribute.String("tavily.topic", stats.Topic))
+	}
+	if stats.Depth != "" {
+		span.SetAttributes(attribute.String("tavily.depth", stats.Depth))
+	}
+	if stats.Err != nil {
+		span.RecordError(stats.Err)
+		span.SetStatus(codes.Error, stats.Err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+}